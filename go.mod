@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/google/go-cmp v0.5.8
 	github.com/stretchr/testify v1.7.2
+	golang.org/x/text v0.13.0
 )
 
 require (
@@ -0,0 +1,89 @@
+package zip
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+)
+
+// adaptiveProbeSize is the size of the first block used to decide whether deflate helps.
+const adaptiveProbeSize = 64 * 1024
+
+// incompressibleExts is the file extensions of already compressed formats.
+var incompressibleExts = map[string]bool{
+	".7z": true, ".apk": true, ".avi": true, ".br": true, ".bz2": true,
+	".docx": true, ".epub": true, ".flac": true, ".gif": true, ".gz": true,
+	".heic": true, ".jar": true, ".jpeg": true, ".jpg": true, ".lz4": true,
+	".m4a": true, ".m4v": true, ".mkv": true, ".mov": true, ".mp3": true,
+	".mp4": true, ".odt": true, ".ogg": true, ".opus": true, ".png": true,
+	".pptx": true, ".rar": true, ".tgz": true, ".webm": true, ".webp": true,
+	".woff": true, ".woff2": true, ".xlsx": true, ".xz": true, ".zip": true,
+	".zst": true,
+}
+
+// isIncompressible returns whether the file name has an extension of an already compressed format.
+func isIncompressible(name string) bool {
+	return incompressibleExts[strings.ToLower(path.Ext(name))]
+}
+
+// deflateHelps returns whether the method compresses data by more than 1/32 of its size.
+func deflateHelps(m MethodType, data []byte) (bool, error) {
+	counter := &countWriter{w: io.Discard}
+	cw, err := m.newCompressor(counter)
+	if err != nil {
+		return false, err
+	}
+	if _, err := cw.Write(data); err != nil {
+		return false, err
+	}
+	if err := cw.Close(); err != nil {
+		return false, err
+	}
+	return counter.Count < len(data)-len(data)/32, nil
+}
+
+// probeWrite buffers p until the first block is filled, and then chooses the method.
+// The rest of p after the first block is written with the chosen method.
+func (fw *fileWriter) probeWrite(p []byte) (int, error) {
+	size := adaptiveProbeSize - fw.probe.Len()
+	if len(p) < size {
+		return fw.probe.Write(p)
+	}
+	n, _ := fw.probe.Write(p[:size])
+	if err := fw.chooseMethod(); err != nil {
+		return n, err
+	}
+	m, err := fw.fw.Write(p[n:])
+	return n + m, err
+}
+
+// chooseMethod switches the file to MethodStore if deflate does not help for the buffered data,
+// writes the file header and the buffered data.
+func (fw *fileWriter) chooseMethod() error {
+	data := fw.probe.Bytes()
+	fw.probe = nil
+
+	helps, err := deflateHelps(fw.fh.Method, data)
+	if err != nil {
+		return err
+	}
+	if !helps {
+		fw.fh.Method = &MethodStore{}
+		fw.fh.MinimumVersion = fw.fh.minimumVersion()
+	}
+
+	if err := fw.writeInit(); err != nil {
+		return err
+	}
+	_, err = fw.fw.Write(data)
+	return err
+}
+
+// newProbe returns the buffer of the first block if the method is chosen adaptively.
+func newProbe(fh *FileHeader) *bytes.Buffer {
+	if fh.Method.ID() != methodDeflatedID {
+		return nil
+	}
+	return bytes.NewBuffer(make([]byte, 0, adaptiveProbeSize))
+}
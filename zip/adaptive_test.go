@@ -0,0 +1,89 @@
+package zip
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestWriterAdaptiveStore(t *testing.T) {
+	random := make([]byte, 100*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := strings.Repeat("hello, world\n", 10000)
+
+	tests := []struct {
+		name     string
+		content  string
+		adaptive bool
+		want     uint16 // method ID
+	}{
+		{"text.txt", text, true, methodDeflatedID},
+		{"random.bin", string(random), true, methodStoreID},
+		{"small-random.bin", string(random[:100]), true, methodStoreID},
+		{"text.jpg", text, true, methodStoreID},
+		{"empty.txt", "", true, methodStoreID},
+		{"random.bin", string(random), false, methodDeflatedID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dd := range []bool{false, true} {
+				for _, chunk := range []int{1000, len(tt.content)} {
+					buf := new(buffer.Buffer)
+					zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{AdaptiveStore: tt.adaptive})
+					if err != nil {
+						t.Fatalf("NewWriterWithOptions error=%v", err)
+					}
+					fh := NewFileHeader(tt.name)
+					fh.Flags.DataDescriptor = dd
+					fw, err := zw.CreateFromHeader(fh)
+					if err != nil {
+						t.Fatalf("Writer.CreateFromHeader error=%v", err)
+					}
+					// write in small pieces across the probe size, or at once beyond the probe size
+					for s := tt.content; len(s) > 0; {
+						n := chunk
+						if n > len(s) {
+							n = len(s)
+						}
+						written, err := fw.Write([]byte(s[:n]))
+						if err != nil {
+							t.Fatalf("FileWriter.Write error=%v", err)
+						}
+						if written != n {
+							t.Fatalf("FileWriter.Write n=%d, want=%d", written, n)
+						}
+						s = s[n:]
+					}
+					if err := fw.Close(); err != nil {
+						t.Fatalf("FileWriter.Close error=%v", err)
+					}
+					if fh.Method.ID() != tt.want {
+						t.Fatalf("FileHeader method=%d, want=%d", fh.Method.ID(), tt.want)
+					}
+					if tt.want == methodStoreID && tt.adaptive && fh.MinimumVersion != 10 {
+						t.Fatalf("MinimumVersion=%d, want=10", fh.MinimumVersion)
+					}
+					if err := zw.Close(); err != nil {
+						t.Fatalf("Writer.Close error=%v", err)
+					}
+
+					zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+					if err != nil {
+						t.Fatalf("NewReader error=%v", err)
+					}
+					f := zr.Files[0]
+					if f.Method.ID() != tt.want {
+						t.Fatalf("File method=%d, want=%d", f.Method.ID(), tt.want)
+					}
+					if got := readAll(t, f); got != tt.content {
+						t.Fatalf("content size=%d, want=%d", len(got), len(tt.content))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package zip
+
+import (
+	"io"
+)
+
+// defaultBufferSize is the default size of the output buffer of zip.Writer.
+const defaultBufferSize = 32 * 1024
+
+// bufferedWriter implements io.WriteSeeker that buffers the written data.
+// Seeking within the buffered data (e.g. rewriting the file header of a small file)
+// does not write to the underlying writer.
+type bufferedWriter struct {
+	w     io.WriteSeeker
+	buf   []byte // buffered data (cap is the buffer size)
+	start int64  // position of buf[0] in w
+	off   int    // current position in buf
+}
+
+// newBufferedWriter returns bufferedWriter with the buffer size.
+func newBufferedWriter(w io.WriteSeeker, size int) (*bufferedWriter, error) {
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedWriter{
+		w:     w,
+		buf:   make([]byte, 0, size),
+		start: start,
+	}, nil
+}
+
+// Write implements the standard Write interface.
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.off+len(p) > cap(b.buf) {
+		if err := b.Flush(); err != nil {
+			return 0, err
+		}
+		if len(p) >= cap(b.buf) {
+			// large data is written directly
+			n, err := b.w.Write(p)
+			b.start += int64(n)
+			return n, err
+		}
+	}
+
+	end := b.off + len(p)
+	if end > len(b.buf) {
+		b.buf = b.buf[:end]
+	}
+	copy(b.buf[b.off:], p)
+	b.off = end
+	return len(p), nil
+}
+
+// Seek implements the standard Seek interface.
+func (b *bufferedWriter) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = b.start + int64(b.off) + offset
+	default:
+		if err := b.Flush(); err != nil {
+			return 0, err
+		}
+		pos, err := b.w.Seek(offset, whence)
+		if err != nil {
+			return 0, err
+		}
+		b.start = pos
+		return pos, nil
+	}
+
+	if abs >= b.start && abs <= b.start+int64(len(b.buf)) {
+		b.off = int(abs - b.start)
+		return abs, nil
+	}
+	if err := b.Flush(); err != nil {
+		return 0, err
+	}
+	pos, err := b.w.Seek(abs, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	b.start = pos
+	return pos, nil
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (b *bufferedWriter) Flush() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	if _, err := b.w.Write(b.buf); err != nil {
+		return err
+	}
+	if b.off != len(b.buf) {
+		// restore the current position after seeking back in the buffer
+		if _, err := b.w.Seek(b.start+int64(b.off), io.SeekStart); err != nil {
+			return err
+		}
+	}
+	b.start += int64(b.off)
+	b.buf = b.buf[:0]
+	b.off = 0
+	return nil
+}
@@ -0,0 +1,147 @@
+package zip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	type op struct {
+		data   string
+		seek   int64
+		whence int
+	}
+	ops := []op{
+		{data: "header"},
+		{data: "0123456789"},
+		{seek: 0, whence: io.SeekStart},
+		{data: "HEADER"},
+		{seek: 0, whence: io.SeekEnd},
+		{data: strings.Repeat("x", 40)},
+		{seek: 6, whence: io.SeekStart}, // flushed position
+		{data: "abc"},
+		{seek: -3, whence: io.SeekCurrent},
+		{data: "ABCDE"},
+		{seek: 0, whence: io.SeekEnd},
+		{data: "tail"},
+	}
+
+	apply := func(w io.WriteSeeker) {
+		for i, o := range ops {
+			if o.data != "" {
+				if _, err := io.WriteString(w, o.data); err != nil {
+					t.Fatalf("ops[%d] Write error=%v", i, err)
+				}
+				continue
+			}
+			pos, err := w.Seek(o.seek, o.whence)
+			if err != nil {
+				t.Fatalf("ops[%d] Seek error=%v", i, err)
+			}
+			if cur, _ := w.Seek(0, io.SeekCurrent); cur != pos {
+				t.Fatalf("ops[%d] position=%d, want=%d", i, cur, pos)
+			}
+		}
+	}
+
+	want := new(buffer.Buffer)
+	apply(buffer.NewWriter(want))
+	for _, size := range []int{1, 8, 16, 1024} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			got := new(buffer.Buffer)
+			bw, err := newBufferedWriter(buffer.NewWriter(got), size)
+			if err != nil {
+				t.Fatalf("newBufferedWriter error=%v", err)
+			}
+			apply(bw)
+			if err := bw.Flush(); err != nil {
+				t.Fatalf("Flush error=%v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Fatalf("written=%q, want=%q", got.Bytes(), want.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriterBufferSize(t *testing.T) {
+	write := func(size int) []byte {
+		buf := new(buffer.Buffer)
+		zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{BufferSize: size})
+		if err != nil {
+			t.Fatalf("NewWriterWithOptions error=%v", err)
+		}
+		for i, content := range []string{"small", strings.Repeat("large content ", 10000), ""} {
+			fh := NewFileHeader(fmt.Sprintf("file%d.txt", i))
+			fh.Flags.DataDescriptor = i%2 == 1
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if _, err := io.WriteString(fw, content); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+			if err := fw.Close(); err != nil {
+				t.Fatalf("FileWriter.Close error=%v", err)
+			}
+			if size >= 1024 && buf.Len() != 0 && i == 0 {
+				t.Fatalf("written size=%d before Flush, want=0", buf.Len())
+			}
+		}
+		if err := zw.Flush(); err != nil {
+			t.Fatalf("Writer.Flush error=%v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Writer.Close error=%v", err)
+		}
+		return buf.Bytes()
+	}
+
+	want := write(-1)
+	for _, size := range []int{0, 16, 100, 1024, 1 << 20} {
+		if got := write(size); !bytes.Equal(got, want) {
+			t.Fatalf("BufferSize=%d archive is different", size)
+		}
+	}
+}
+
+func benchmarkWriterFile(b *testing.B, size int) {
+	dir := b.TempDir()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Create(filepath.Join(dir, "bench.zip"))
+		if err != nil {
+			b.Fatalf("os.Create error=%v", err)
+		}
+		zw, err := NewWriterWithOptions(file, &WriterOptions{BufferSize: size, AllowDuplicates: true})
+		if err != nil {
+			b.Fatalf("NewWriterWithOptions error=%v", err)
+		}
+		for j := 0; j < 1000; j++ {
+			fw, err := zw.Create(fmt.Sprintf("file%d.txt", j))
+			if err != nil {
+				b.Fatalf("Writer.Create error=%v", err)
+			}
+			fmt.Fprintf(fw, "small file %d", j)
+		}
+		if err := zw.Close(); err != nil {
+			b.Fatalf("Writer.Close error=%v", err)
+		}
+		file.Close()
+	}
+}
+
+func BenchmarkWriterFileUnbuffered(b *testing.B) {
+	benchmarkWriterFile(b, -1)
+}
+
+func BenchmarkWriterFileBuffered(b *testing.B) {
+	benchmarkWriterFile(b, 0)
+}
@@ -0,0 +1,302 @@
+package zip
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/fs"
+	"math"
+	"sync"
+)
+
+const (
+	defaultCacheBlockSize = 64 * 1024        // default size of a cached block
+	defaultCacheTailSize  = 256 * 1024       // default size of the prefetched tail
+	defaultCacheMaxMemory = 16 * 1024 * 1024 // default memory budget of cached blocks
+
+	// minCacheTailSize is the size searched for the end of central directory record.
+	minCacheTailSize = math.MaxUint16 + sizeEndCentralDirectory
+)
+
+// CachedReaderOptions represents options of zip.CachedReader.
+type CachedReaderOptions struct {
+	// BlockSize is the unit of reading and caching. Small reads are rounded up to blocks,
+	// and adjacent missing blocks are read in one call.
+	// If 0, 64 KiB is used.
+	BlockSize int
+
+	// TailSize is the size of the end of the file prefetched in one call.
+	// If the central directory starts before the tail, the rest is read in another call.
+	// The tail and the central directory are kept apart from MaxMemory.
+	// If 0, 256 KiB is used. It is at least the size searched for the end of central directory record.
+	TailSize int
+
+	// MaxMemory is the memory budget of the cached blocks.
+	// The least recently used blocks are evicted, and larger reads are not cached.
+	// If 0, 16 MiB is used.
+	MaxMemory int64
+}
+
+// CachedReader is a read-ahead caching layer for high-latency io.ReaderAt sources
+// (e.g. HTTP range requests to object storage). It implements io.ReaderAt and io.ReadSeeker,
+// so it can be passed to NewReader. ReadAt is safe for concurrent use.
+type CachedReader struct {
+	r    io.ReaderAt
+	size int64
+	sr   *io.SectionReader // position for Read and Seek
+
+	blockSize int64
+	maxMemory int64
+
+	tail *tailData // prefetched tail and central directory (not modified after NewCachedReader)
+
+	mu       sync.Mutex
+	blocks   map[int64]*list.Element // cached blocks by index
+	lru      *list.List              // cached blocks, most recently used first
+	used     int64                   // memory used by the cached blocks
+	fetching map[int64]*cacheFetch   // blocks being read from the source by index
+}
+
+// cacheBlock is a cached block.
+type cacheBlock struct {
+	index int64
+	data  []byte
+}
+
+// NewCachedReader returns zip.CachedReader that reads from io.ReaderAt of size bytes.
+// The tail of the file is prefetched.
+// If opts is nil, the default options are used.
+func NewCachedReader(r io.ReaderAt, size int64, opts *CachedReaderOptions) (*CachedReader, error) {
+	if opts == nil {
+		opts = &CachedReaderOptions{}
+	}
+	if size < 0 || opts.BlockSize < 0 || opts.TailSize < 0 || opts.MaxMemory < 0 {
+		return nil, wrapError(fs.ErrInvalid, "negative size or options: size=%d %+v", size, *opts)
+	}
+
+	c := &CachedReader{
+		r:         r,
+		size:      size,
+		blockSize: int64(opts.BlockSize),
+		maxMemory: opts.MaxMemory,
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+		fetching:  make(map[int64]*cacheFetch),
+	}
+	if c.blockSize == 0 {
+		c.blockSize = defaultCacheBlockSize
+	}
+	if c.maxMemory == 0 {
+		c.maxMemory = defaultCacheMaxMemory
+	}
+	c.sr = io.NewSectionReader(c, 0, size)
+
+	tailSize := int64(opts.TailSize)
+	if tailSize == 0 {
+		tailSize = defaultCacheTailSize
+	}
+	if tailSize < int64(minCacheTailSize) {
+		tailSize = int64(minCacheTailSize)
+	}
+	if err := c.prefetchTail(tailSize); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// prefetchTail reads the tail of the file, and the central directory before the tail.
+func (c *CachedReader) prefetchTail(size int64) error {
+	if size > c.size {
+		size = c.size
+	}
+	buf := make([]byte, size)
+	if _, err := c.r.ReadAt(buf, c.size-size); err != nil && err != io.EOF {
+		return err
+	}
+	c.tail = &tailData{offset: c.size - size, buf: buf}
+
+	// the central directory is located just before the end of central directory record.
+	// the record is validated by Reader, so the last signature is used here.
+	index := bytes.LastIndex(buf, []byte(signEndCentralDirectory))
+	if index < 0 {
+		return nil
+	}
+	enddir := new(endCentralDirectory)
+	if _, err := enddir.ReadFrom(bytes.NewReader(buf[index:])); err != nil {
+		return nil // not a record, Reader reports the error
+	}
+	start := c.tail.offset + int64(index) - int64(enddir.sizeOfCentralDirectories)
+	if start < 0 || start >= c.tail.offset {
+		return nil
+	}
+	head := make([]byte, c.tail.offset-start)
+	if _, err := c.r.ReadAt(head, start); err != nil {
+		return err
+	}
+	c.tail.buf = append(head, c.tail.buf...)
+	c.tail.offset = start
+	return nil
+}
+
+// ReadAt implements the standard ReadAt interface.
+// The lock is not held while reading from the source, and a block being read by
+// another goroutine is waited for instead of being read again.
+func (c *CachedReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, wrapError(fs.ErrInvalid, "negative offset %d", off)
+	}
+	if off >= c.size {
+		return 0, io.EOF
+	}
+	var eof error
+	if rest := c.size - off; int64(len(p)) > rest {
+		p = p[:rest]
+		eof = io.EOF
+	}
+
+	// the tail is not modified after NewCachedReader
+	end := off + int64(len(p))
+	if off >= c.tail.offset && end <= c.tail.offset+int64(len(c.tail.buf)) {
+		copy(p, c.tail.from(off))
+		return len(p), eof
+	}
+
+	first, last := off/c.blockSize, (end-1)/c.blockSize
+	if (last-first+1)*c.blockSize > c.maxMemory {
+		// too large to cache
+		n, err := c.r.ReadAt(p, off)
+		if err == nil {
+			err = eof
+		}
+		return n, err
+	}
+
+	blocks, err := c.fetch(first, last)
+	if err != nil {
+		return 0, err
+	}
+	for n := 0; n < len(p); {
+		pos := off + int64(n)
+		index := pos / c.blockSize
+		n += copy(p[n:], blocks[index-first][pos-index*c.blockSize:])
+	}
+	return len(p), eof
+}
+
+// cacheFetch is a read of adjacent blocks from the source in progress.
+type cacheFetch struct {
+	first int64         // index of the first block
+	buf   []byte        // data of the blocks (valid after done is closed)
+	err   error         // error of the read (valid after done is closed)
+	done  chan struct{} // closed when the read is completed
+}
+
+// block returns the data of the block with index in the fetch.
+func (f *cacheFetch) block(index, blockSize int64) []byte {
+	data := f.buf[(index-f.first)*blockSize:]
+	if int64(len(data)) > blockSize {
+		data = data[:blockSize]
+	}
+	return data
+}
+
+// fetch returns the data of the blocks from first to last, and reads the missing blocks.
+// Adjacent missing blocks are read in one call.
+func (c *CachedReader) fetch(first, last int64) ([][]byte, error) {
+	blocks := make([][]byte, last-first+1)
+	waits := make(map[int64]*cacheFetch)
+	owns := make([]*cacheFetch, 0)
+
+	c.mu.Lock()
+	for index := first; index <= last; {
+		if e, ok := c.blocks[index]; ok {
+			c.lru.MoveToFront(e)
+			blocks[index-first] = e.Value.(*cacheBlock).data
+			index++
+			continue
+		}
+		if f, ok := c.fetching[index]; ok {
+			waits[index] = f
+			index++
+			continue
+		}
+		f := &cacheFetch{first: index, done: make(chan struct{})}
+		for ; index <= last; index++ {
+			if _, ok := c.blocks[index]; ok {
+				break
+			}
+			if _, ok := c.fetching[index]; ok {
+				break
+			}
+			c.fetching[index] = f
+			waits[index] = f
+		}
+		f.buf = make([]byte, 0, (index-f.first)*c.blockSize)
+		owns = append(owns, f)
+	}
+	c.mu.Unlock()
+
+	for _, f := range owns {
+		c.read(f)
+	}
+	for index, f := range waits {
+		<-f.done
+		if f.err != nil {
+			return nil, f.err
+		}
+		blocks[index-first] = f.block(index, c.blockSize)
+	}
+	return blocks, nil
+}
+
+// read reads the blocks of the fetch from the source, and adds them to the cache.
+func (c *CachedReader) read(f *cacheFetch) {
+	start := f.first * c.blockSize
+	size := int64(cap(f.buf))
+	if start+size > c.size {
+		size = c.size - start
+	}
+	buf := f.buf[:size]
+	if _, err := c.r.ReadAt(buf, start); err != nil && err != io.EOF {
+		f.err = err
+	}
+	f.buf = buf
+
+	c.mu.Lock()
+	count := (int64(cap(f.buf)) + c.blockSize - 1) / c.blockSize
+	for index := f.first; index < f.first+count; index++ {
+		delete(c.fetching, index)
+		if f.err == nil {
+			// each block has its own memory to be evicted separately
+			c.add(&cacheBlock{index: index, data: append([]byte(nil), f.block(index, c.blockSize)...)})
+		}
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// add adds the block, and evicts the least recently used blocks over the memory budget.
+func (c *CachedReader) add(block *cacheBlock) {
+	for c.used+int64(len(block.data)) > c.maxMemory && c.lru.Len() > 0 {
+		old := c.lru.Remove(c.lru.Back()).(*cacheBlock)
+		delete(c.blocks, old.index)
+		c.used -= int64(len(old.data))
+	}
+	c.blocks[block.index] = c.lru.PushFront(block)
+	c.used += int64(len(block.data))
+}
+
+// Read implements the standard Read interface.
+func (c *CachedReader) Read(p []byte) (int, error) {
+	return c.sr.Read(p)
+}
+
+// Seek implements the standard Seek interface.
+func (c *CachedReader) Seek(offset int64, whence int) (int64, error) {
+	return c.sr.Seek(offset, whence)
+}
+
+// Size returns the size of the file.
+func (c *CachedReader) Size() int64 {
+	return c.size
+}
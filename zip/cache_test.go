@@ -0,0 +1,196 @@
+package zip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+// countReaderAt implements io.ReaderAt that counts ReadAt calls (e.g. HTTP range requests).
+type countReaderAt struct {
+	r     io.ReaderAt
+	mu    sync.Mutex
+	calls int
+}
+
+// ReadAt implements the standard ReadAt interface.
+func (r *countReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+	return r.r.ReadAt(p, off)
+}
+
+func TestCachedReader(t *testing.T) {
+	random := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	for i := 0; i < 4; i++ {
+		fh := NewFileHeader(fmt.Sprintf("data%d.bin", i))
+		fh.Method = &MethodStore{}
+		fw, err := zw.CreateFromHeader(fh)
+		if err != nil {
+			t.Fatalf("Writer.CreateFromHeader error=%v", err)
+		}
+		if _, err := fw.Write(random); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	for i := 0; i < 6000; i++ {
+		if _, err := zw.Create(fmt.Sprintf("dir/small%04d.txt", i)); err != nil {
+			t.Fatalf("Writer.Create error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	// the tail and the central directory before the tail
+	counter := &countReaderAt{r: bytes.NewReader(src)}
+	cr, err := NewCachedReader(counter, int64(len(src)), &CachedReaderOptions{MaxMemory: 512 * 1024})
+	if err != nil {
+		t.Fatalf("NewCachedReader error=%v", err)
+	}
+	if counter.calls != 2 {
+		t.Fatalf("prefetch calls=%d, want=2", counter.calls)
+	}
+	zr, err := NewReader(cr)
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	if counter.calls != 2 {
+		t.Fatalf("NewReader calls=%d, want=2", counter.calls)
+	}
+
+	// small reads are coalesced, and cached reads are not read again
+	f, err := zr.Lookup("data0.bin")
+	if err != nil {
+		t.Fatalf("Reader.Lookup error=%v", err)
+	}
+	rc, err := f.OpenRaw()
+	if err != nil {
+		t.Fatalf("File.OpenRaw error=%v", err)
+	}
+	head := make([]byte, 1000)
+	for i := 0; i < 50; i++ {
+		if _, err := io.ReadFull(rc, head); err != nil {
+			t.Fatalf("Read error=%v", err)
+		}
+	}
+	if calls := counter.calls - 2; calls != 1 {
+		t.Fatalf("small read calls=%d, want=1", calls)
+	}
+	calls := counter.calls
+	if got := readAll(t, f); got != string(random) {
+		t.Fatalf("content is different")
+	}
+	if cr.used > 512*1024 {
+		t.Fatalf("cached memory=%d, want<=%d", cr.used, 512*1024)
+	}
+	if counter.calls-calls > 1<<20/defaultCacheBlockSize+2 {
+		t.Fatalf("sequential read calls=%d", counter.calls-calls)
+	}
+
+	// random reads from multiple goroutines
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < 200; i++ {
+				off := rnd.Int63n(int64(len(src)))
+				p := make([]byte, rnd.Intn(200*1024))
+				n, err := cr.ReadAt(p, off)
+				want := src[off:]
+				if len(want) > len(p) {
+					want = want[:len(p)]
+				}
+				if n != len(want) || !bytes.Equal(p[:n], want) {
+					t.Errorf("ReadAt(%d, %d) n=%d error=%v, content is different", len(p), off, n, err)
+					return
+				}
+				if n < len(p) && err != io.EOF {
+					t.Errorf("ReadAt(%d, %d) error=%v, want=%v", len(p), off, err, io.EOF)
+					return
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+	if cr.used > 512*1024 {
+		t.Fatalf("cached memory=%d, want<=%d", cr.used, 512*1024)
+	}
+}
+
+// blockingReaderAt implements io.ReaderAt that blocks the reads out of the tail until released.
+type blockingReaderAt struct {
+	countReaderAt
+	tail    int64
+	started chan struct{}
+	release chan struct{}
+}
+
+// ReadAt implements the standard ReadAt interface.
+func (r *blockingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < r.tail {
+		r.started <- struct{}{}
+		<-r.release
+	}
+	return r.countReaderAt.ReadAt(p, off)
+}
+
+func TestCachedReaderConcurrentFetch(t *testing.T) {
+	src := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(src)
+	tail := int64(len(src) - defaultCacheTailSize)
+
+	r := &blockingReaderAt{
+		countReaderAt: countReaderAt{r: bytes.NewReader(src)},
+		tail:          tail,
+		started:       make(chan struct{}, 10),
+		release:       make(chan struct{}),
+	}
+	cr, err := NewCachedReader(r, int64(len(src)), nil)
+	if err != nil {
+		t.Fatalf("NewCachedReader error=%v", err)
+	}
+	calls := r.calls
+
+	// the same block is read from the source once
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 100)
+			if _, err := cr.ReadAt(p, 1000); err != nil || !bytes.Equal(p, src[1000:1100]) {
+				t.Errorf("ReadAt error=%v, content is different", err)
+			}
+		}()
+	}
+	<-r.started
+
+	// the lock is not held while reading from the source
+	p := make([]byte, 100)
+	if _, err := cr.ReadAt(p, tail); err != nil || !bytes.Equal(p, src[tail:tail+100]) {
+		t.Fatalf("ReadAt error=%v, content is different", err)
+	}
+
+	close(r.release)
+	wg.Wait()
+	if got := r.calls - calls; got != 1 {
+		t.Fatalf("source calls=%d, want=1", got)
+	}
+}
@@ -0,0 +1,74 @@
+package zip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrFormat       = errors.New("zip: not a valid zip file")               // broken or invalid zip format
+	ErrChecksum     = errors.New("zip: checksum error")                     // CRC-32 or size mismatch
+	ErrAlgorithm    = errors.New("zip: unsupported compression algorithm")  // unknown compression method or level
+	ErrUnsupported  = errors.New("zip: unsupported feature")                // valid but unsupported zip feature
+	ErrInsecurePath = errors.New("zip: insecure file path")                 // file path escapes the destination
+	ErrPassword     = errors.New("zip: encrypted file requires a password") // encrypted file
+	ErrDuplicate    = errors.New("zip: duplicate file name")                // file name conflicts with a written name
+)
+
+// Error represents an error with the file name and the byte offset in the zip archive.
+// Err wraps one of the sentinel errors (ErrFormat, ErrChecksum, ...), so
+// errors.Is can be used to distinguish the kind of the error.
+type Error struct {
+	Name   string // file name (empty if the error is not for a file)
+	Offset int64  // byte offset in the zip archive (-1 if unknown)
+	Err    error  // underlying error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	where := make([]string, 0, 2)
+	if e.Name != "" {
+		where = append(where, fmt.Sprintf("file %q", e.Name))
+	}
+	if e.Offset >= 0 {
+		where = append(where, fmt.Sprintf("offset %d", e.Offset))
+	}
+	if len(where) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (%s)", e.Err, strings.Join(where, ", "))
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError returns *Error that wraps kind with the detail message.
+func newError(kind error, name string, offset int64, format string, args ...any) error {
+	return &Error{
+		Name:   name,
+		Offset: offset,
+		Err:    wrapError(kind, format, args...),
+	}
+}
+
+// wrapError returns an error that wraps kind with the detail message.
+func wrapError(kind error, format string, args ...any) error {
+	return fmt.Errorf("%w: %s", kind, fmt.Sprintf(format, args...))
+}
+
+// withPosition returns *Error that adds the file name and the offset to err.
+// If err is already *Error, err is returned as is.
+func withPosition(err error, name string, offset int64) error {
+	var zerr *Error
+	if errors.As(err, &zerr) {
+		return err
+	}
+	return &Error{
+		Name:   name,
+		Offset: offset,
+		Err:    err,
+	}
+}
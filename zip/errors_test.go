@@ -0,0 +1,148 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestErrors(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	fh := NewFileHeader("a.txt")
+	fh.Method = &MethodStore{}
+	fw, err := zw.CreateFromHeader(fh)
+	if err != nil {
+		t.Fatalf("Writer.CreateFromHeader error=%v", err)
+	}
+	if _, err := fw.Write([]byte("hello world")); err != nil {
+		t.Fatalf("FileWriter.Write error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+	cdir := bytes.Index(src, []byte(signCentralDirectoryHeader))
+	enddir := bytes.LastIndex(src, []byte(signEndCentralDirectory))
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+		want   error
+		file   string // expected file name of *Error (empty if the error is not *Error)
+		offset int    // expected offset of *Error
+	}{
+		{
+			"not-zip",
+			func(b []byte) []byte { return []byte("not a zip file") },
+			ErrFormat,
+			"",
+			0,
+		},
+		{
+			"checksum",
+			func(b []byte) []byte {
+				b[bytes.Index(b, []byte("hello"))] = 'j'
+				return b
+			},
+			ErrChecksum,
+			"a.txt",
+			0,
+		},
+		{
+			"algorithm",
+			func(b []byte) []byte {
+				b[8], b[cdir+10] = 99, 99
+				return b
+			},
+			ErrAlgorithm,
+			"a.txt",
+			cdir,
+		},
+		{
+			"password",
+			func(b []byte) []byte {
+				b[6] |= 0x01
+				b[cdir+8] |= 0x01
+				return b
+			},
+			ErrPassword,
+			"a.txt",
+			0,
+		},
+		{
+			"split",
+			func(b []byte) []byte {
+				b[enddir+4] = 1
+				return b
+			},
+			ErrUnsupported,
+			"",
+			0,
+		},
+		{
+			"truncated-local",
+			func(b []byte) []byte {
+				b[26], b[27] = 0xff, 0xff
+				return b
+			},
+			ErrFormat,
+			"a.txt",
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.modify(append([]byte{}, src...))
+
+			err := func() error {
+				zr, err := NewReader(bytes.NewReader(b))
+				if err != nil {
+					return err
+				}
+				r, err := zr.Files[0].Open()
+				if err != nil {
+					return err
+				}
+				defer r.Close()
+				_, err = io.ReadAll(r)
+				return err
+			}()
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error=%v, want=%v", err, tt.want)
+			}
+			var zerr *Error
+			if errors.As(err, &zerr) != (tt.file != "") {
+				t.Fatalf("error=%v, want *Error=%v", err, tt.file != "")
+			}
+			if tt.file != "" && (zerr.Name != tt.file || zerr.Offset != int64(tt.offset)) {
+				t.Fatalf("Error name=%q offset=%d, want=%q offset=%d", zerr.Name, zerr.Offset, tt.file, tt.offset)
+			}
+		})
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	zw, err := NewWriter(buffer.NewWriter(new(buffer.Buffer)))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+
+	if _, err := zw.Create("../escape.txt"); !errors.Is(err, ErrInsecurePath) {
+		t.Fatalf("Writer.Create error=%v, want=%v", err, ErrInsecurePath)
+	}
+
+	fh := NewFileHeader("secret.txt")
+	fh.Flags.Encrypted = true
+	if _, err := zw.CreateFromHeader(fh); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Writer.CreateFromHeader error=%v, want=%v", err, ErrUnsupported)
+	}
+}
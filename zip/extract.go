@@ -0,0 +1,199 @@
+package zip
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Extract extracts all files in the zip archive to the directory dir.
+// File names must be relative paths that stay inside dir.
+// Symbolic links are created after all other files, and the link target
+// must also be a relative path that stays inside dir.
+// Files are never written through a symbolic link.
+func (r *Reader) Extract(dir string) error {
+	links := make([]*File, 0)
+	it := r.Iterate()
+	for it.Next() {
+		f := it.File()
+		if f.IsSymlink() {
+			links = append(links, f)
+			continue
+		}
+		if err := extractFile(dir, f); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range links {
+		if err := extractSymlink(dir, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile extracts the file or directory to dir.
+func extractFile(dir string, f *File) error {
+	name, err := extractPath(dir, f.FileName)
+	if err != nil {
+		return err
+	}
+
+	mode := f.Mode()
+	perm := mode.Perm()
+	if mode.IsDir() {
+		return os.MkdirAll(name, perm|0700)
+	}
+	if !mode.IsRegular() {
+		return newError(ErrUnsupported, f.FileName, f.offset, "unsupported file type")
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(name); err == nil {
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return newError(ErrInsecurePath, f.FileName, -1, "destination file is a symbolic link")
+		}
+		if info.Mode().IsRegular() {
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// O_EXCL does not follow a symbolic link created after the check above
+	w, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if f.ModifiedTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(name, f.ModifiedTime, f.ModifiedTime)
+}
+
+// extractSymlink creates the symbolic link in dir.
+func extractSymlink(dir string, f *File) error {
+	name, err := extractPath(dir, f.FileName)
+	if err != nil {
+		return err
+	}
+
+	target, err := f.LinkTarget()
+	if err != nil {
+		return err
+	}
+	if path.IsAbs(target) || filepath.IsAbs(target) || strings.Contains(target, `\`) {
+		return newError(ErrInsecurePath, f.FileName, -1, "symbolic link target %q", target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := checkLinkTarget(dir, path.Dir(strings.TrimSuffix(f.FileName, "/")), target); err != nil {
+		return withPosition(err, f.FileName, -1)
+	}
+	return os.Symlink(filepath.FromSlash(target), name)
+}
+
+// checkLinkTarget returns an error if the link target in the directory parent escapes dir.
+// The target is resolved component by component against the files already in dir,
+// because a lexical check is fooled by the symbolic links extracted before (e.g. "l2" -> "."
+// and "l1" -> "l2/.."). The target must not pass through a symbolic link, and ".." must not
+// follow a missing component that may become a symbolic link later.
+func checkLinkTarget(dir, parent, target string) error {
+	resolved := make([]string, 0)
+	if parent != "." {
+		resolved = strings.Split(parent, "/")
+	}
+
+	elems := strings.Split(target, "/")
+	missing := false
+	for i, elem := range elems {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if missing || len(resolved) == 0 {
+				return wrapError(ErrInsecurePath, "symbolic link target %q", target)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, elem)
+		if missing {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(strings.Join(resolved, "/"))))
+		if os.IsNotExist(err) {
+			missing = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// the last component may be a symbolic link that is already checked
+		if info.Mode()&fs.ModeSymlink != 0 && i != len(elems)-1 {
+			return wrapError(ErrInsecurePath, "symbolic link target %q passes through a symbolic link", target)
+		}
+	}
+	return nil
+}
+
+// extractPath returns the destination path of the file name in dir.
+// It returns an error if the name escapes dir or passes through a symbolic link.
+func extractPath(dir string, name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if !fs.ValidPath(name) || name == "." {
+		return "", wrapError(ErrInsecurePath, "%q", name)
+	}
+	// backslashes and volume names are separators on some systems
+	if strings.Contains(name, `\`) || filepath.VolumeName(name) != "" {
+		return "", wrapError(ErrInsecurePath, "%q", name)
+	}
+
+	// parent directories must not be symbolic links
+	elems := strings.Split(name, "/")
+	current := dir
+	for _, elem := range elems[:len(elems)-1] {
+		current = filepath.Join(current, elem)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", wrapError(ErrInsecurePath, "%q passes through a symbolic link", name)
+		}
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,254 @@
+package zip
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestReaderExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		links  map[string]string
+		failed bool
+	}{
+		{
+			name: "relative-link",
+			links: map[string]string{
+				"dir/link": "file.txt",
+				"dir/up":   "../dir/file.txt",
+				"alias":    "top",
+				"top":      "dir",
+			},
+		},
+		{
+			name:   "absolute-link",
+			links:  map[string]string{"link": "/etc/passwd"},
+			failed: true,
+		},
+		{
+			name:   "escape-link",
+			links:  map[string]string{"dir/link": "../../outside"},
+			failed: true,
+		},
+		{
+			name: "through-link",
+			links: map[string]string{
+				"a":   "dir",
+				"a/b": "file.txt",
+			},
+			failed: true,
+		},
+		{
+			// lexically "l2/.." stays in the directory, but l2 is a link to "."
+			name: "chain-link",
+			links: map[string]string{
+				"l2": ".",
+				"l1": "l2/..",
+			},
+			failed: true,
+		},
+		{
+			name:   "missing-parent-link",
+			links:  map[string]string{"link": "missing/../dir/file.txt"},
+			failed: true,
+		},
+		{
+			name: "through-target-link",
+			links: map[string]string{
+				"top": "dir",
+				"tz":  "top/../file.txt",
+			},
+			failed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			// malicious archives may have a link and a file under it
+			zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{AllowDuplicates: true})
+			if err != nil {
+				t.Fatalf("NewWriterWithOptions error=%v", err)
+			}
+			if _, err := zw.Create("dir/"); err != nil {
+				t.Fatalf("Writer.Create error=%v", err)
+			}
+			fw, err := zw.Create("dir/file.txt")
+			if err != nil {
+				t.Fatalf("Writer.Create error=%v", err)
+			}
+			if _, err := fw.Write([]byte("content")); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+			for _, name := range sortedKeys(tt.links) {
+				if err := zw.CreateSymlink(name, tt.links[name]); err != nil {
+					t.Fatalf("Writer.CreateSymlink error=%v", err)
+				}
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReader(buffer.NewReader(buf))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			for _, f := range zr.Files {
+				target, ok := tt.links[f.FileName]
+				if f.IsSymlink() != ok {
+					t.Fatalf("%s: IsSymlink=%v, want=%v", f.FileName, f.IsSymlink(), ok)
+				}
+				if !ok {
+					continue
+				}
+				got, err := f.LinkTarget()
+				if err != nil {
+					t.Fatalf("%s: LinkTarget error=%v", f.FileName, err)
+				}
+				if got != target {
+					t.Errorf("%s: LinkTarget=%q, want=%q", f.FileName, got, target)
+				}
+			}
+
+			dir := t.TempDir()
+			err = zr.Extract(dir)
+			if tt.failed {
+				if !errors.Is(err, ErrInsecurePath) {
+					t.Fatalf("Extract error=%v, want=%v", err, ErrInsecurePath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract error=%v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "top", "link"))
+			if err != nil {
+				t.Fatalf("ReadFile error=%v", err)
+			}
+			if string(data) != "content" {
+				t.Errorf("content=%q, want=%q", data, "content")
+			}
+			for name, target := range tt.links {
+				got, err := os.Readlink(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("Readlink error=%v", err)
+				}
+				if got != target {
+					t.Errorf("%s: link=%q, want=%q", name, got, target)
+				}
+			}
+		})
+	}
+}
+
+func TestReaderExtractInsecureName(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows bool
+	}{
+		{name: `..\..\evil`},
+		{name: `dir\..\..\evil`},
+		{name: `C:\evil`},
+		{name: "C:evil", windows: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.windows && runtime.GOOS != "windows" {
+				t.Skip("volume names are only used on windows")
+			}
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			if _, err := zw.Create(tt.name); err != nil {
+				t.Fatalf("Writer.Create error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+			zr, err := NewReader(buffer.NewReader(buf))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+
+			dir := t.TempDir()
+			if err := zr.Extract(dir); !errors.Is(err, ErrInsecurePath) {
+				t.Fatalf("Extract error=%v, want=%v", err, ErrInsecurePath)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("ReadDir error=%v", err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("ReadDir len=%d, want=0", len(entries))
+			}
+		})
+	}
+}
+
+func TestReaderExtractOverwrite(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	fw, err := zw.Create("file.txt")
+	if err != nil {
+		t.Fatalf("Writer.Create error=%v", err)
+	}
+	if _, err := fw.Write([]byte("content")); err != nil {
+		t.Fatalf("FileWriter.Write error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	zr, err := NewReader(buffer.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+
+	// an existing file is replaced, and an existing link is not followed
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(name, []byte("old content"), 0644); err != nil {
+		t.Fatalf("WriteFile error=%v", err)
+	}
+	if err := zr.Extract(dir); err != nil {
+		t.Fatalf("Extract error=%v", err)
+	}
+	if data, err := os.ReadFile(name); err != nil || string(data) != "content" {
+		t.Fatalf("content=%q error=%v, want=%q", data, err, "content")
+	}
+
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.Remove(name); err != nil {
+		t.Fatalf("Remove error=%v", err)
+	}
+	if err := os.Symlink(outside, name); err != nil {
+		t.Fatalf("Symlink error=%v", err)
+	}
+	if err := zr.Extract(dir); !errors.Is(err, ErrInsecurePath) {
+		t.Fatalf("Extract error=%v, want=%v", err, ErrInsecurePath)
+	}
+	if _, err := os.Lstat(outside); err == nil {
+		t.Fatalf("file is written through the symbolic link")
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,8 +2,6 @@ package zip
 
 import (
 	"bytes"
-	"errors"
-	"fmt"
 	"io"
 	"time"
 
@@ -33,6 +31,8 @@ func parseExtraFields(buf []byte) ([]ExtraField, error) {
 		switch e.Tag() {
 		case extraNTFSTag:
 			extra = &ExtraNTFS{}
+		case extraExtendedTimestampTag:
+			extra = &ExtraExtendedTimestamp{}
 		default:
 			extra = nil
 		}
@@ -94,6 +94,9 @@ func (e ExtraUnknown) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
+// extraZip64Tag is the tag ID of ZIP64 extended information extra field.
+const extraZip64Tag uint16 = 0x0001
+
 // extraNTFSTag is the tag ID of NTFS extra field.
 const extraNTFSTag uint16 = 0x000a
 
@@ -124,7 +127,7 @@ func (e *ExtraNTFS) ReadFrom(r io.Reader) (int64, error) {
 	byteio.GetUint16LE(br, &tag)
 	byteio.GetUint16LE(br, &size)
 	if tag != extraNTFSTag {
-		return 0, errors.New("extra field is not NTFS")
+		return 0, wrapError(ErrFormat, "extra field is not NTFS")
 	}
 
 	buf = make([]byte, size)
@@ -144,10 +147,10 @@ func (e *ExtraNTFS) ReadFrom(r io.Reader) (int64, error) {
 	byteio.GetUint16LE(br, &ttag)
 	byteio.GetUint16LE(br, &tsize)
 	if ttag != 0x0001 {
-		return 0, fmt.Errorf("undefined NTFS attributes: %0x", ttag)
+		return 0, wrapError(ErrFormat, "undefined NTFS attributes: %0x", ttag)
 	}
 	if tsize != 24 {
-		return 0, fmt.Errorf("unexpected NTFS attributes size: %d", tsize)
+		return 0, wrapError(ErrFormat, "unexpected NTFS attributes size: %d", tsize)
 	}
 	byteio.GetUint64LE(br, &mtime)
 	byteio.GetUint64LE(br, &atime)
@@ -177,13 +180,116 @@ func (e ExtraNTFS) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
+// extraExtendedTimestampTag is the tag ID of extended timestamp extra field.
+const extraExtendedTimestampTag uint16 = 0x5455
+
+const (
+	extendedTimestampMtime uint8 = 0x01 // flag for modification time
+	extendedTimestampAtime uint8 = 0x02 // flag for access time
+	extendedTimestampCtime uint8 = 0x04 // flag for creation time
+)
+
+// ExtraExtendedTimestamp represents a extra field for extended timestamp (UNIX time).
+// Zero time values are not written.
+type ExtraExtendedTimestamp struct {
+	Mtime time.Time // last modification time
+	Atime time.Time // last access time
+	Ctime time.Time // creation time
+}
+
+// Tag returns the tag ID of the extra field.
+func (e ExtraExtendedTimestamp) Tag() uint16 {
+	return extraExtendedTimestampTag
+}
+
+// ReadFrom reads the extra field from the reader.
+func (e *ExtraExtendedTimestamp) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+
+	var (
+		tag  uint16
+		size uint16
+	)
+	br := bytes.NewReader(buf)
+	byteio.GetUint16LE(br, &tag)
+	byteio.GetUint16LE(br, &size)
+	if tag != extraExtendedTimestampTag {
+		return 0, wrapError(ErrFormat, "extra field is not extended timestamp")
+	}
+	if size < 1 {
+		return 0, wrapError(ErrFormat, "unexpected extended timestamp size: %d", size)
+	}
+
+	buf = make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	br = bytes.NewReader(buf)
+
+	var flags uint8
+	byteio.GetUint8(br, &flags)
+
+	// central directory header has only the modification time,
+	// even if the flags indicate other times.
+	targets := []struct {
+		flag uint8
+		t    *time.Time
+	}{
+		{extendedTimestampMtime, &e.Mtime},
+		{extendedTimestampAtime, &e.Atime},
+		{extendedTimestampCtime, &e.Ctime},
+	}
+	for _, target := range targets {
+		if flags&target.flag == 0 || br.Len() < 4 {
+			continue
+		}
+		var t uint32
+		byteio.GetUint32LE(br, &t)
+		*target.t = time.Unix(int64(int32(t)), 0)
+	}
+
+	return 4 + int64(size), nil
+}
+
+// WriteTo writes the extra field to the writer.
+func (e ExtraExtendedTimestamp) WriteTo(w io.Writer) (int64, error) {
+	var flags uint8
+	data := new(bytes.Buffer)
+	for _, target := range []struct {
+		flag uint8
+		t    time.Time
+	}{
+		{extendedTimestampMtime, e.Mtime},
+		{extendedTimestampAtime, e.Atime},
+		{extendedTimestampCtime, e.Ctime},
+	} {
+		if target.t.IsZero() {
+			continue
+		}
+		flags |= target.flag
+		byteio.WriteUint32LE(data, uint32(int32(target.t.Unix())))
+	}
+
+	buf := new(bytes.Buffer)
+	byteio.WriteUint16LE(buf, extraExtendedTimestampTag)
+	byteio.WriteUint16LE(buf, uint16(1+data.Len()))
+	byteio.WriteUint8(buf, flags)
+	buf.Write(data.Bytes())
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
 // uint64ToWin32Time converts a uint64 to a win32 system time.
 func uint64ToWin32Time(t uint64) time.Time {
 	unixstart := uint64(0x019DB1DED53E8000)
 	ticks := uint64(10000000)
 
 	sec := int64((t - unixstart) / ticks)
-	nsec := int64(t % ticks)
+	nsec := int64(t%ticks) * 100
 	return time.Unix(sec, nsec)
 }
 
@@ -193,6 +299,6 @@ func uint64FromWin32Time(t time.Time) uint64 {
 	ticks := uint64(10000000)
 
 	utime := (uint64(t.Unix()) * ticks) + unixstart
-	utime += uint64(t.Nanosecond())
+	utime += uint64(t.Nanosecond()) / 100
 	return utime
 }
@@ -59,9 +59,9 @@ func TestExtraNTFS(t *testing.T) {
 				F8 4B B4 5E 7A D8 01 D3-5D 02 F1 5F 7A D8 01 9B
 				F8 4B B4 5E 7A D8 01
 			`),
-			mtime: time.Date(2022, 6, 7, 11, 6, 57, 7821851, time.UTC),
-			ctime: time.Date(2022, 6, 7, 11, 6, 57, 7821851, time.UTC),
-			atime: time.Date(2022, 6, 7, 11, 15, 49, 1375571, time.UTC),
+			mtime: time.Date(2022, 6, 7, 11, 6, 57, 782185100, time.UTC),
+			ctime: time.Date(2022, 6, 7, 11, 6, 57, 782185100, time.UTC),
+			atime: time.Date(2022, 6, 7, 11, 15, 49, 137557100, time.UTC),
 		},
 	}
 
@@ -97,3 +97,57 @@ func TestExtraNTFS(t *testing.T) {
 		}
 	}
 }
+
+func TestExtraExtendedTimestamp(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		mtime time.Time
+		atime time.Time
+	}{
+		{
+			data: hexToBytes(`
+				55 54 09 00 03 f0 15 75 62 00 16 75 62
+			`),
+			mtime: time.Date(2022, 5, 6, 12, 34, 56, 0, time.UTC),
+			atime: time.Date(2022, 5, 6, 12, 35, 12, 0, time.UTC),
+		},
+		{
+			data: hexToBytes(`
+				55 54 05 00 01 f0 15 75 62
+			`),
+			mtime: time.Date(2022, 5, 6, 12, 34, 56, 0, time.UTC),
+		},
+	}
+
+	for i, test := range tests {
+		var e ExtraExtendedTimestamp
+
+		r := bytes.NewReader(test.data)
+		if _, err := e.ReadFrom(r); err != nil {
+			t.Fatalf("table#%d ReadFrom: %v", i, err)
+		}
+		if e.Mtime.Equal(test.mtime) == false {
+			t.Errorf("table#%d Mtime=%v, want=%v", i, e.Mtime, test.mtime)
+		}
+		if e.Atime.Equal(test.atime) == false {
+			t.Errorf("table#%d Atime=%v, want=%v", i, e.Atime, test.atime)
+		}
+		if e.Ctime.IsZero() == false {
+			t.Errorf("table#%d Ctime=%v, want zero", i, e.Ctime)
+		}
+
+		w := new(bytes.Buffer)
+		if _, err := e.WriteTo(w); err != nil {
+			t.Fatalf("table#%d WriteTo: %v", i, err)
+		}
+		if w.Len() != len(test.data) {
+			t.Fatalf("table#%d WriteTo: written=%d, want=%d", i, w.Len(), len(test.data))
+		}
+		out := w.Bytes()
+		for j := range test.data {
+			if out[j] != test.data[j] {
+				t.Fatalf("table#%d WriteTo: write[%d]=%x, want=%x", i, j, out[j], test.data[j])
+			}
+		}
+	}
+}
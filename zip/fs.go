@@ -0,0 +1,325 @@
+package zip
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SymlinkPolicy represents how AddFS handles symbolic links.
+type SymlinkPolicy int
+
+const (
+	SymlinkSkip   SymlinkPolicy = iota // skip symbolic links
+	SymlinkFollow                      // add the file or directory that the link points to
+	SymlinkStore                       // add the link itself (link target is stored as content)
+)
+
+// maxSymlinkFollow is the maximum nesting depth of followed symbolic links,
+// and the maximum number of symbolic links resolved in a path.
+const maxSymlinkFollow = 40
+
+// AddFSOptions represents options of Writer.AddFS.
+type AddFSOptions struct {
+	Method            MethodType    // compression method for files (nil is deflate)
+	Exclude           []string      // path.Match patterns matched against the relative path and the base name
+	Symlink           SymlinkPolicy // symbolic link policy
+	NTFSTime          bool          // add ExtraNTFS field
+	ExtendedTimestamp bool          // add ExtraExtendedTimestamp field
+}
+
+// readLinkFS is the interface implemented by a file system
+// that supports reading symbolic links.
+type readLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// lstatFS is the interface implemented by a file system
+// that returns fs.FileInfo without following symbolic links.
+type lstatFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// AddFS adds the files in the directory tree root of fsys to the writer.
+// The file names in the zip archive are relative to root.
+// Entries are added in lexical order, and directories are added as entries.
+// With SymlinkFollow, a link to a directory being walked (a loop) is stored as a link
+// if fsys can read symbolic links.
+// If opts is nil, the default options are used.
+// If the previous io.WriteCloser has not called Close, it is forced to close.
+func (w *Writer) AddFS(fsys fs.FS, root string, opts *AddFSOptions) error {
+	if opts == nil {
+		opts = &AddFSOptions{}
+	}
+	if !fs.ValidPath(root) {
+		return wrapError(ErrInsecurePath, "root path %q", root)
+	}
+
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return wrapError(fs.ErrInvalid, "root path is not a directory: %q", root)
+	}
+
+	a := &fsAdder{
+		w:       w,
+		fsys:    fsys,
+		root:    root,
+		opts:    opts,
+		walking: make([]string, 0),
+	}
+	resolved, err := a.realPath(root)
+	if err != nil {
+		return err
+	}
+	return a.walkDir(root, resolved, 0)
+}
+
+// fsAdder adds the files in fs.FS to zip.Writer.
+type fsAdder struct {
+	w       *Writer
+	fsys    fs.FS
+	root    string
+	opts    *AddFSOptions
+	walking []string // real paths of the directories being walked
+}
+
+// walkDir adds the entries in the directory dir.
+// resolved is the path of dir with symbolic links resolved (empty if unknown).
+func (a *fsAdder) walkDir(dir, resolved string, depth int) error {
+	entries, err := fs.ReadDir(a.fsys, dir)
+	if err != nil {
+		return err
+	}
+	if resolved != "" {
+		a.walking = append(a.walking, resolved)
+		defer func() { a.walking = a.walking[:len(a.walking)-1] }()
+	}
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if a.excluded(name) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		child := ""
+		if resolved != "" {
+			child = path.Join(resolved, entry.Name())
+		}
+		if err := a.add(name, child, info, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the entry name. resolved is the path of name with the parent directories resolved.
+func (a *fsAdder) add(name, resolved string, info fs.FileInfo, depth int) error {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		switch a.opts.Symlink {
+		case SymlinkSkip:
+			return nil
+		case SymlinkStore:
+			return a.addSymlink(name, info)
+		case SymlinkFollow:
+			if depth >= maxSymlinkFollow {
+				return wrapError(fs.ErrInvalid, "too many levels of symbolic links: %q", name)
+			}
+			target, err := fs.Stat(a.fsys, name)
+			if err != nil {
+				return err
+			}
+			if target.IsDir() {
+				resolved, err := a.realPath(name)
+				if err != nil {
+					return err
+				}
+				if a.isWalking(resolved) {
+					// the link points back to a directory being walked
+					return a.addSymlink(name, info)
+				}
+				if err := a.addDir(name, target); err != nil {
+					return err
+				}
+				return a.walkDir(name, resolved, depth+1)
+			}
+			return a.addFile(name, target)
+		default:
+			return wrapError(fs.ErrInvalid, "unknown symlink policy: %d", a.opts.Symlink)
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		if err := a.addDir(name, info); err != nil {
+			return err
+		}
+		return a.walkDir(name, resolved, depth)
+	case info.Mode().IsRegular():
+		return a.addFile(name, info)
+	}
+	return wrapError(ErrUnsupported, "file type of %q", name)
+}
+
+// realPath returns the path of name in fsys with all symbolic links resolved.
+// It returns an empty string if fsys can not read symbolic links,
+// or the link points outside fsys.
+func (a *fsAdder) realPath(name string) (string, error) {
+	lfs, ok := a.fsys.(readLinkFS)
+	if !ok {
+		return "", nil
+	}
+	sfs, ok := a.fsys.(lstatFS)
+	if !ok {
+		return "", nil
+	}
+
+	resolved := "."
+	rest := strings.Split(name, "/")
+	for links := 0; len(rest) > 0; {
+		elem := rest[0]
+		rest = rest[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, elem)
+		info, err := sfs.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxSymlinkFollow {
+			return "", wrapError(fs.ErrInvalid, "too many levels of symbolic links: %q", name)
+		}
+		target, err := lfs.ReadLink(next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", nil
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// isWalking returns whether the directory resolved is being walked.
+func (a *fsAdder) isWalking(resolved string) bool {
+	if resolved == "" {
+		return false
+	}
+	for _, dir := range a.walking {
+		if dir == resolved {
+			return true
+		}
+	}
+	return false
+}
+
+// addDir adds the directory entry.
+func (a *fsAdder) addDir(name string, info fs.FileInfo) error {
+	fw, err := a.w.CreateFromHeader(a.header(name+"/", info))
+	if err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// addFile adds the regular file entry.
+func (a *fsAdder) addFile(name string, info fs.FileInfo) error {
+	fh := a.header(name, info)
+	if a.opts.Method != nil {
+		fh.Method = a.opts.Method
+	}
+
+	fw, err := a.w.CreateFromHeader(fh)
+	if err != nil {
+		return err
+	}
+
+	f, err := a.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(fw, f); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// addSymlink adds the symbolic link entry.
+func (a *fsAdder) addSymlink(name string, info fs.FileInfo) error {
+	lfs, ok := a.fsys.(readLinkFS)
+	if !ok {
+		return wrapError(ErrUnsupported, "file system does not support reading symbolic links")
+	}
+	target, err := lfs.ReadLink(name)
+	if err != nil {
+		return err
+	}
+
+	return a.w.createSymlinkFromHeader(a.header(name, info), target)
+}
+
+// header returns a new FileHeader for the entry.
+func (a *fsAdder) header(name string, info fs.FileInfo) *FileHeader {
+	fh := NewFileHeader(a.relative(name))
+	fh.ModifiedTime = info.ModTime()
+	fh.GenerateOS = OS_UNIX
+	fh.SetMode(info.Mode())
+
+	if a.opts.NTFSTime {
+		fh.ExtraFields = append(fh.ExtraFields, &ExtraNTFS{
+			Mtime: info.ModTime(),
+			Atime: info.ModTime(),
+			Ctime: info.ModTime(),
+		})
+	}
+	if a.opts.ExtendedTimestamp {
+		fh.ExtraFields = append(fh.ExtraFields, &ExtraExtendedTimestamp{
+			Mtime: info.ModTime(),
+		})
+	}
+	return fh
+}
+
+// relative returns the name relative to the root.
+func (a *fsAdder) relative(name string) string {
+	if a.root == "." {
+		return name
+	}
+	return name[len(a.root)+1:]
+}
+
+// excluded returns whether the entry name matches the exclude patterns.
+func (a *fsAdder) excluded(name string) bool {
+	rel := a.relative(name)
+	for _, pattern := range a.opts.Exclude {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
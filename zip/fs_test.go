@@ -0,0 +1,196 @@
+package zip
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-mylib/buffer"
+)
+
+func TestWriterAddFS(t *testing.T) {
+	mtime := time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"root/b.txt":         {Data: []byte("file b"), Mode: 0644, ModTime: mtime},
+		"root/a/c.txt":       {Data: []byte("file c"), Mode: 0755, ModTime: mtime},
+		"root/a/skip.log":    {Data: []byte("skip"), Mode: 0644, ModTime: mtime},
+		"root/link":          {Data: []byte("b.txt"), Mode: fs.ModeSymlink | 0777, ModTime: mtime},
+		"root/a":             {Mode: fs.ModeDir | 0755, ModTime: mtime},
+		"root/empty":         {Mode: fs.ModeDir | 0700, ModTime: mtime},
+		"root/a/nested":      {Mode: fs.ModeDir | 0755, ModTime: mtime},
+		"other/ignore.txt":   {Data: []byte("ignore"), Mode: 0644, ModTime: mtime},
+		"root/.git/HEAD":     {Data: []byte("ref"), Mode: 0644, ModTime: mtime},
+		"root/.git/config":   {Data: []byte("cfg"), Mode: 0644, ModTime: mtime},
+		"root/a/nested/d.gz": {Data: []byte("file d"), Mode: 0600, ModTime: mtime},
+	}
+
+	tests := []struct {
+		name   string
+		opts   *AddFSOptions
+		expect []string
+	}{
+		{
+			name: "skip-symlink",
+			opts: &AddFSOptions{Exclude: []string{"*.log", ".git"}},
+			expect: []string{
+				"a/", "a/c.txt", "a/nested/", "a/nested/d.gz", "b.txt", "empty/",
+			},
+		},
+		{
+			name: "store-symlink",
+			opts: &AddFSOptions{Exclude: []string{"*.log", ".git", "a"}, Symlink: SymlinkStore},
+			expect: []string{
+				"b.txt", "empty/", "link",
+			},
+		},
+		{
+			name: "follow-symlink",
+			opts: &AddFSOptions{Exclude: []string{"*.log", ".git", "a"}, Symlink: SymlinkFollow},
+			expect: []string{
+				"b.txt", "empty/", "link",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			if err := zw.AddFS(fsys, "root", tt.opts); err != nil {
+				t.Fatalf("AddFS error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReader(buffer.NewReader(buf))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			if len(zr.Files) != len(tt.expect) {
+				t.Fatalf("Reader.Files size=%d, want=%d", len(zr.Files), len(tt.expect))
+			}
+			for i, f := range zr.Files {
+				if f.FileName != tt.expect[i] {
+					t.Errorf("Files[%d] name=%q, want=%q", i, f.FileName, tt.expect[i])
+				}
+				if f.GenerateOS != OS_UNIX {
+					t.Errorf("%s: GenerateOS=%d, want=%d", f.FileName, f.GenerateOS, OS_UNIX)
+				}
+				if !f.ModifiedTime.Equal(mtime) {
+					t.Errorf("%s: ModifiedTime=%v, want=%v", f.FileName, f.ModifiedTime, mtime)
+				}
+			}
+
+			for _, f := range zr.Files {
+				unix := ExternalFileAttribute(f.ExternalFileAttr).Unix()
+				switch f.FileName {
+				case "a/c.txt":
+					if unix != 0100755 {
+						t.Errorf("%s: mode=%o, want=%o", f.FileName, unix, 0100755)
+					}
+				case "empty/":
+					if unix != 0040700 {
+						t.Errorf("%s: mode=%o, want=%o", f.FileName, unix, 0040700)
+					}
+				case "link":
+					want := "file b"
+					wantMode := uint16(0100644)
+					if tt.opts.Symlink == SymlinkStore {
+						want = "b.txt"
+						wantMode = 0120777
+					}
+					if unix != wantMode {
+						t.Errorf("%s: mode=%o, want=%o", f.FileName, unix, wantMode)
+					}
+					if got := readAll(t, f); got != want {
+						t.Errorf("%s: content=%q, want=%q", f.FileName, got, want)
+					}
+				case "b.txt":
+					if got := readAll(t, f); got != "file b" {
+						t.Errorf("%s: content=%q, want=%q", f.FileName, got, "file b")
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriterAddFSSymlinkLoop(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/self":        {Data: []byte("."), Mode: fs.ModeSymlink | 0777},
+		"root/dir/file":    {Data: []byte("content"), Mode: 0644},
+		"root/dir/up":      {Data: []byte("../../root"), Mode: fs.ModeSymlink | 0777},
+		"root/dir/alias":   {Data: []byte("../other"), Mode: fs.ModeSymlink | 0777},
+		"root/other/back":  {Data: []byte("../dir"), Mode: fs.ModeSymlink | 0777},
+		"root/other/file2": {Data: []byte("content2"), Mode: 0644},
+	}
+
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	if err := zw.AddFS(fsys, "root", &AddFSOptions{Symlink: SymlinkFollow}); err != nil {
+		t.Fatalf("AddFS error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+
+	// the links back to the directories being walked are stored as links
+	links := map[string]string{
+		"dir/alias/back":   "../dir",
+		"dir/up":           "../../root",
+		"other/back/alias": "../other",
+		"other/back/up":    "../../root",
+		"self":             ".",
+	}
+	expect := []string{
+		"dir/", "dir/alias/", "dir/alias/back", "dir/alias/file2", "dir/file", "dir/up",
+		"other/", "other/back/", "other/back/alias", "other/back/file", "other/back/up", "other/file2",
+		"self",
+	}
+	zr, err := NewReader(buffer.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	names := make([]string, 0, len(zr.Files))
+	for _, f := range zr.Files {
+		names = append(names, f.FileName)
+		target, ok := links[f.FileName]
+		if f.IsSymlink() != ok {
+			t.Errorf("%s: IsSymlink=%v, want=%v", f.FileName, f.IsSymlink(), ok)
+			continue
+		}
+		if got, _ := f.LinkTarget(); ok && got != target {
+			t.Errorf("%s: LinkTarget=%q, want=%q", f.FileName, got, target)
+		}
+	}
+	if strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Fatalf("files=%q, want=%q", names, expect)
+	}
+}
+
+func readAll(t *testing.T, f *File) string {
+	t.Helper()
+
+	r, err := f.Open()
+	if err != nil {
+		t.Fatalf("%s: Open error=%v", f.FileName, err)
+	}
+	defer r.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, r); err != nil {
+		t.Fatalf("%s: read error=%v", f.FileName, err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,259 @@
+package zip
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler is http.Handler that serves the files in the zip archive (e.g. a packaged static site).
+//
+// The URL path is mapped to the file name, and "index.html" is served for directories.
+// The ETag is made from the CRC-32, and conditional requests (If-None-Match, If-Modified-Since)
+// are supported. Range requests are supported for the stored files.
+// If the client accepts deflate, the deflated files are sent as is with Content-Encoding: deflate
+// (raw deflate data), otherwise they are decompressed.
+type Handler struct {
+	r       *Reader
+	ra      io.ReaderAt // the zip archive
+	mu      sync.Mutex  // guards the position of the zip archive
+	indexed bool        // files are looked up in the name index without the lock
+}
+
+// NewHandler returns zip.Handler that serves the files in zip.Reader.
+// The name index of the Reader is built here, so the Reader should not be modified after this call.
+// If the source of the Reader implements io.ReaderAt, the file data is read concurrently.
+func NewHandler(r *Reader) *Handler {
+	h := &Handler{r: r}
+	// in lazy mode, the files are read from the central directory at lookup
+	// if the index is not built, it is retried at lookup, and the error is reported there
+	h.indexed = r.buildIndexOnce() == nil && !r.lazy
+	if ra, ok := r.r.(io.ReaderAt); ok {
+		h.ra = ra
+	} else {
+		h.ra = &lockedReaderAt{r: r.r, mu: &h.mu}
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+	if name == "" || strings.HasSuffix(req.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+	f, err := h.lookup(name)
+	if err != nil && path.Base(name) != "index.html" {
+		if _, ierr := h.lookup(path.Join(name, "index.html")); ierr == nil {
+			// redirect to the directory
+			localRedirect(w, req, path.Base(name)+"/")
+			return
+		}
+	}
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	data, err := h.dataSection(f)
+	if err != nil {
+		http.Error(w, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%08x"`, f.CRC32)
+	contentType := mime.TypeByExtension(path.Ext(f.FileName))
+	switch f.Method.ID() {
+	case methodStoreID:
+		w.Header().Set("ETag", etag)
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		http.ServeContent(w, req, f.FileName, f.ModifiedTime, data)
+		return
+	case methodDeflatedID:
+	default:
+		http.Error(w, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// deflated file
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Add("Vary", "Accept-Encoding")
+	if !f.ModifiedTime.IsZero() {
+		header.Set("Last-Modified", f.ModifiedTime.UTC().Format(http.TimeFormat))
+	}
+
+	deflate := acceptsDeflate(req.Header.Get("Accept-Encoding"))
+	size := int64(f.UncompressedSize)
+	if deflate {
+		etag = fmt.Sprintf(`"%08x-deflate"`, f.CRC32)
+		size = int64(f.CompressedSize)
+		header.Set("Content-Encoding", "deflate")
+	}
+	header.Set("ETag", etag)
+	if notModified(req, etag, f.ModifiedTime) {
+		header.Del("Content-Type")
+		header.Del("Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	// the status is already sent, so the errors abort the response
+	// not to let the clients cache the truncated body
+	if deflate {
+		if _, err := io.Copy(w, data); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+	dr, err := f.Method.newDecompressor(data)
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	var r io.ReadCloser = &checksumReader{
+		r:    dr,
+		f:    f,
+		hash: crc32.NewIEEE(),
+	}
+	if f.limit != nil {
+		r = f.limit.newReader(f, r)
+	}
+	defer r.Close()
+	if _, err := io.Copy(w, r); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// lookup returns the regular file with name.
+func (h *Handler) lookup(name string) (*File, error) {
+	if !h.indexed {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+	}
+
+	f, err := h.r.File(name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Mode().IsRegular() || f.Flags.Encrypted {
+		return nil, fmt.Errorf("%q is not a regular file", name)
+	}
+	return f, nil
+}
+
+// dataSection returns the compressed data of the file.
+func (h *Handler) dataSection(f *File) (*io.SectionReader, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	offset, err := f.dataOffset()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(h.ra, offset, int64(f.CompressedSize)), nil
+}
+
+// lockedReaderAt implements io.ReaderAt by seeking io.ReadSeeker with the lock.
+type lockedReaderAt struct {
+	r  io.ReadSeeker
+	mu *sync.Mutex
+}
+
+// ReadAt implements the standard ReadAt interface.
+func (r *lockedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// acceptsDeflate returns whether Accept-Encoding accepts deflate.
+// An explicit deflate takes precedence over "*", and q=0 refuses the coding.
+func acceptsDeflate(accept string) bool {
+	deflate, wildcard := -1.0, -1.0 // q-values (negative if not listed)
+	for _, coding := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "deflate" && name != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.ToLower(key) == "q" {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		if name == "deflate" {
+			deflate = q
+		} else {
+			wildcard = q
+		}
+	}
+	if deflate >= 0 {
+		return deflate > 0
+	}
+	return wildcard > 0
+}
+
+// notModified returns whether the conditional request matches the file.
+// If-None-Match takes precedence over If-Modified-Since.
+func notModified(req *http.Request, etag string, modtime time.Time) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := req.Header.Get("If-Modified-Since")
+	if ims == "" || modtime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modtime.Truncate(time.Second).After(t)
+}
+
+// localRedirect redirects to the relative path with the query.
+func localRedirect(w http.ResponseWriter, req *http.Request, target string) {
+	if q := req.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
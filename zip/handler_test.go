@@ -0,0 +1,178 @@
+package zip
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-mylib/buffer"
+)
+
+func TestHandler(t *testing.T) {
+	mtime := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	files := []struct {
+		name    string
+		content string
+		method  MethodType
+	}{
+		{"index.html", "<html>top</html>", &MethodDeflated{}},
+		{"css/style.css", strings.Repeat("body { color: red; }\n", 10), &MethodDeflated{}},
+		{"docs/index.html", "<html>docs</html>", &MethodDeflated{}},
+		{"image.png", "\x89PNG\r\n\x1a\n0123456789", &MethodStore{}},
+		{"empty/", "", &MethodStore{}},
+	}
+
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	for _, file := range files {
+		fh := NewFileHeader(file.name)
+		fh.Method = file.method
+		fh.ModifiedTime = mtime
+		fw, err := zw.CreateFromHeader(fh)
+		if err != nil {
+			t.Fatalf("Writer.CreateFromHeader error=%v", err)
+		}
+		if _, err := io.WriteString(fw, file.content); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   map[string]string
+		status   int
+		body     string
+		encoding string
+		want     map[string]string // response headers
+	}{
+		{"deflated", "GET", "/css/style.css", nil, 200, files[1].content, "",
+			map[string]string{"Content-Type": "text/css; charset=utf-8", "Vary": "Accept-Encoding"}},
+		{"passthrough", "GET", "/css/style.css", map[string]string{"Accept-Encoding": "gzip, deflate"}, 200, files[1].content, "deflate", nil},
+		{"deflate-refused", "GET", "/css/style.css", map[string]string{"Accept-Encoding": "deflate;q=0"}, 200, files[1].content, "", nil},
+		{"deflate-any", "GET", "/css/style.css", map[string]string{"Accept-Encoding": "gzip, *;q=0.5"}, 200, files[1].content, "deflate", nil},
+		{"deflate-refused-any", "GET", "/css/style.css", map[string]string{"Accept-Encoding": "*;q=0.5, deflate;q=0"}, 200, files[1].content, "", nil},
+		{"deflate-over-any", "GET", "/css/style.css", map[string]string{"Accept-Encoding": "*;q=0, deflate"}, 200, files[1].content, "deflate", nil},
+		{"index", "GET", "/", nil, 200, files[0].content, "", nil},
+		{"directory-index", "GET", "/docs/", nil, 200, files[2].content, "", nil},
+		{"directory-redirect", "GET", "/docs?a=1", nil, 301, "", "", map[string]string{"Location": "docs/?a=1"}},
+		{"directory-no-index", "GET", "/empty/", nil, 404, "", "", nil},
+		{"not-found", "GET", "/missing.txt", nil, 404, "", "", nil},
+		{"escape", "GET", "/../index.html", nil, 200, files[0].content, "", nil},
+		{"method", "POST", "/index.html", nil, 405, "", "", map[string]string{"Allow": "GET, HEAD"}},
+		{"head", "HEAD", "/index.html", nil, 200, "", "", map[string]string{"Content-Length": "16"}},
+		{"stored", "GET", "/image.png", nil, 200, files[3].content, "", map[string]string{"Content-Type": "image/png"}},
+		{"range", "GET", "/image.png", map[string]string{"Range": "bytes=8-11"}, 206, "0123", "",
+			map[string]string{"Content-Range": "bytes 8-11/18"}},
+		{"etag", "GET", "/index.html", map[string]string{"If-None-Match": fmt.Sprintf(`"00000000", "%08x"`, crc32.ChecksumIEEE([]byte(files[0].content)))}, 304, "", "", nil},
+		{"etag-mismatch", "GET", "/index.html", map[string]string{"If-None-Match": `"00000000"`}, 200, files[0].content, "", nil},
+		{"modified-since", "GET", "/index.html", map[string]string{"If-Modified-Since": mtime.Format(http.TimeFormat)}, 304, "", "", nil},
+		{"modified", "GET", "/index.html", map[string]string{"If-Modified-Since": mtime.Add(-time.Hour).Format(http.TimeFormat)}, 200, files[0].content, "", nil},
+		{"stored-modified-since", "GET", "/image.png", map[string]string{"If-Modified-Since": mtime.Format(http.TimeFormat)}, 304, "", "", nil},
+	}
+
+	readers := map[string]func() (*Reader, error){
+		"ReaderAt":   func() (*Reader, error) { return NewReader(bytes.NewReader(src)) },
+		"ReadSeeker": func() (*Reader, error) { return NewReader(struct{ io.ReadSeeker }{bytes.NewReader(src)}) },
+		"Lazy": func() (*Reader, error) {
+			return NewReaderWithOptions(bytes.NewReader(src), &ReaderOptions{Lazy: true})
+		},
+	}
+	for rname, newReader := range readers {
+		zr, err := newReader()
+		if err != nil {
+			t.Fatalf("NewReader error=%v", err)
+		}
+		handler := NewHandler(zr)
+
+		for _, tt := range tests {
+			t.Run(rname+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				for key, value := range tt.header {
+					req.Header.Set(key, value)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				res := rec.Result()
+				if res.StatusCode != tt.status {
+					t.Fatalf("status=%d, want=%d", res.StatusCode, tt.status)
+				}
+				for key, value := range tt.want {
+					if got := res.Header.Get(key); got != value {
+						t.Fatalf("header %s=%q, want=%q", key, got, value)
+					}
+				}
+				if got := res.Header.Get("Content-Encoding"); got != tt.encoding {
+					t.Fatalf("Content-Encoding=%q, want=%q", got, tt.encoding)
+				}
+				if tt.status != 200 && tt.status != 206 {
+					return
+				}
+
+				body := rec.Body.Bytes()
+				if tt.encoding == "deflate" {
+					if res.Header.Get("ETag") == "" || !strings.HasSuffix(res.Header.Get("ETag"), `-deflate"`) {
+						t.Fatalf("ETag=%q, want deflate variant", res.Header.Get("ETag"))
+					}
+					decoded, err := io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+					if err != nil {
+						t.Fatalf("flate read error=%v", err)
+					}
+					body = decoded
+				}
+				if string(body) != tt.body {
+					t.Fatalf("body=%q, want=%q", body, tt.body)
+				}
+			})
+		}
+	}
+}
+
+func TestHandlerAbort(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	fw, err := zw.Create("index.html")
+	if err != nil {
+		t.Fatalf("Writer.Create error=%v", err)
+	}
+	if _, err := io.WriteString(fw, strings.Repeat("<p>broken</p>\n", 100)); err != nil {
+		t.Fatalf("FileWriter.Write error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+
+	zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	zr.Files[0].CRC32 ^= 1
+
+	// the checksum error after the status is sent aborts the response
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Fatalf("recover=%v, want=%v", r, http.ErrAbortHandler)
+		}
+	}()
+	NewHandler(zr).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/index.html", nil))
+	t.Fatalf("ServeHTTP returned, want panic")
+}
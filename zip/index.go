@@ -0,0 +1,137 @@
+package zip
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// nameIndex is the index of file names.
+// If names are duplicated, the first file in the central directory is used.
+type nameIndex struct {
+	keys []string         // sorted name keys
+	pos  map[string]int64 // position of the file by name key
+}
+
+// nameKey returns the key to compare the file name.
+// In case-insensitive mode, the name is lower-cased and backslashes are replaced with slashes.
+func (r *Reader) nameKey(name string) string {
+	if !r.fold {
+		return name
+	}
+	return strings.ToLower(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// BuildIndex builds the name index used by Lookup, File, Glob and ListDir.
+// In lazy mode, the index holds only the position of each file, not the file itself.
+func (r *Reader) BuildIndex() error {
+	index := &nameIndex{
+		keys: make([]string, 0, r.NumFiles()),
+		pos:  make(map[string]int64, r.NumFiles()),
+	}
+	it := r.Iterate()
+	for it.Next() {
+		key := r.nameKey(it.File().FileName)
+		if _, ok := index.pos[key]; !ok {
+			index.keys = append(index.keys, key)
+			index.pos[key] = it.cur
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	sort.Strings(index.keys)
+
+	r.index = index
+	return nil
+}
+
+// buildIndexOnce builds the name index if it is not built.
+func (r *Reader) buildIndexOnce() error {
+	if r.index != nil {
+		return nil
+	}
+	return r.BuildIndex()
+}
+
+// File returns the file with name using the name index.
+// The index is built at the first call.
+// If the file is not found, the error wraps fs.ErrNotExist.
+func (r *Reader) File(name string) (*File, error) {
+	if err := r.buildIndexOnce(); err != nil {
+		return nil, err
+	}
+	return r.Lookup(name)
+}
+
+// Glob returns the files whose names match pattern, sorted by name.
+// The pattern syntax is the same as in path.Match.
+func (r *Reader) Glob(pattern string) ([]*File, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if err := r.buildIndexOnce(); err != nil {
+		return nil, err
+	}
+
+	pattern = r.nameKey(pattern)
+	return r.indexFiles(func(key string) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	})
+}
+
+// ListDir returns the files under the directory dir (including subdirectories), sorted by name.
+// If dir is "" or ".", all files are returned.
+func (r *Reader) ListDir(dir string) ([]*File, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "listdir", Path: dir, Err: fs.ErrInvalid}
+	}
+	if err := r.buildIndexOnce(); err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if dir != "." {
+		prefix = r.nameKey(dir + "/")
+	}
+	start := sort.SearchStrings(r.index.keys, prefix)
+	end := start
+	for end < len(r.index.keys) && strings.HasPrefix(r.index.keys[end], prefix) {
+		end++
+	}
+
+	files := make([]*File, 0, end-start)
+	for _, key := range r.index.keys[start:end] {
+		if key == prefix {
+			// directory itself
+			continue
+		}
+		f, _, err := r.fileAt(r.index.pos[key])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// indexFiles returns the files whose name keys satisfy match, sorted by name.
+func (r *Reader) indexFiles(match func(key string) bool) ([]*File, error) {
+	files := make([]*File, 0)
+	for _, key := range r.index.keys {
+		if !match(key) {
+			continue
+		}
+		f, _, err := r.fileAt(r.index.pos[key])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
@@ -0,0 +1,123 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestReaderIndex(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{AllowDuplicates: true})
+	if err != nil {
+		t.Fatalf("NewWriterWithOptions error=%v", err)
+	}
+	names := []string{"other.txt", "Dir/", "Dir/sub/B.txt", "Dir/A.txt", "Dir2/c.txt", "other.txt"}
+	for i, name := range names {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Writer.Create error=%v", err)
+		}
+		if _, err := fmt.Fprintf(fw, "content %d", i); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	fileNames := func(files []*File) []string {
+		names := make([]string, 0, len(files))
+		for _, f := range files {
+			names = append(names, f.FileName)
+		}
+		return names
+	}
+
+	for _, lazy := range []bool{false, true} {
+		for _, fold := range []bool{false, true} {
+			t.Run(fmt.Sprintf("lazy=%v,fold=%v", lazy, fold), func(t *testing.T) {
+				zr, err := NewReaderWithOptions(bytes.NewReader(src), &ReaderOptions{Lazy: lazy, CaseInsensitive: fold})
+				if err != nil {
+					t.Fatalf("NewReaderWithOptions error=%v", err)
+				}
+
+				f, err := zr.File("Dir/A.txt")
+				if err != nil {
+					t.Fatalf("Reader.File error=%v", err)
+				}
+				if got := readAll(t, f); got != "content 3" {
+					t.Fatalf("content=%q, want=%q", got, "content 3")
+				}
+
+				// duplicate name
+				f, err = zr.File("other.txt")
+				if err != nil {
+					t.Fatalf("Reader.File error=%v", err)
+				}
+				if got := readAll(t, f); got != "content 0" {
+					t.Fatalf("duplicate content=%q, want=%q", got, "content 0")
+				}
+
+				// case-insensitive
+				for _, name := range []string{"dir/a.txt", `DIR\A.TXT`} {
+					f, err = zr.File(name)
+					if fold && (err != nil || f.FileName != "Dir/A.txt") {
+						t.Fatalf("Reader.File(%q) error=%v", name, err)
+					}
+					if !fold && !errors.Is(err, fs.ErrNotExist) {
+						t.Fatalf("Reader.File(%q) error=%v, want=%v", name, err, fs.ErrNotExist)
+					}
+				}
+
+				globs := []struct {
+					pattern string
+					want    []string
+				}{
+					{"Dir/*.txt", []string{"Dir/A.txt"}},
+					{"*/*.txt", []string{"Dir/A.txt", "Dir2/c.txt"}},
+					{"*.txt", []string{"other.txt"}},
+					{"nothing", []string{}},
+				}
+				for _, g := range globs {
+					files, err := zr.Glob(g.pattern)
+					if err != nil {
+						t.Fatalf("Reader.Glob(%q) error=%v", g.pattern, err)
+					}
+					if got := fileNames(files); fmt.Sprint(got) != fmt.Sprint(g.want) {
+						t.Fatalf("Reader.Glob(%q)=%v, want=%v", g.pattern, got, g.want)
+					}
+				}
+				if _, err := zr.Glob("["); !errors.Is(err, path.ErrBadPattern) {
+					t.Fatalf("Reader.Glob error=%v, want=%v", err, path.ErrBadPattern)
+				}
+
+				lists := []struct {
+					dir  string
+					want []string
+				}{
+					{"Dir", []string{"Dir/A.txt", "Dir/sub/B.txt"}},
+					{"Dir/sub", []string{"Dir/sub/B.txt"}},
+					{".", []string{"Dir/", "Dir/A.txt", "Dir/sub/B.txt", "Dir2/c.txt", "other.txt"}},
+					{"", []string{"Dir/", "Dir/A.txt", "Dir/sub/B.txt", "Dir2/c.txt", "other.txt"}},
+					{"none", []string{}},
+				}
+				for _, l := range lists {
+					files, err := zr.ListDir(l.dir)
+					if err != nil {
+						t.Fatalf("Reader.ListDir(%q) error=%v", l.dir, err)
+					}
+					if got := fileNames(files); fmt.Sprint(got) != fmt.Sprint(l.want) {
+						t.Fatalf("Reader.ListDir(%q)=%v, want=%v", l.dir, got, l.want)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package zip
+
+import (
+	"io/fs"
+)
+
+// FileIterator iterates over the files in the order of the central directory.
+// In lazy mode, each file is read from the central directory when Next is called.
+//
+//	it := r.Iterate()
+//	for it.Next() {
+//		f := it.File()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FileIterator struct {
+	r    *Reader
+	n    int   // number of files read
+	pos  int64 // position of the next file
+	cur  int64 // position of the current file
+	file *File
+	err  error
+}
+
+// Iterate returns FileIterator over the files in the zip archive.
+func (r *Reader) Iterate() *FileIterator {
+	it := &FileIterator{r: r}
+	if r.lazy {
+		it.pos = r.dirOffset
+	}
+	return it
+}
+
+// Next advances to the next file, and returns whether the file is available.
+// It returns false at the end of the files or on an error.
+func (it *FileIterator) Next() bool {
+	it.file = nil
+	if it.err != nil || it.n >= it.r.NumFiles() {
+		return false
+	}
+
+	it.cur = it.pos
+	it.file, it.pos, it.err = it.r.fileAt(it.pos)
+	if it.err != nil {
+		it.file = nil
+		return false
+	}
+	it.n++
+	return true
+}
+
+// File returns the current file.
+func (it *FileIterator) File() *File {
+	return it.file
+}
+
+// Err returns the error that stopped the iteration.
+func (it *FileIterator) Err() error {
+	return it.err
+}
+
+// NumFiles returns the number of files in the zip archive.
+func (r *Reader) NumFiles() int {
+	if r.lazy {
+		return r.entries
+	}
+	return len(r.Files)
+}
+
+// Lookup returns the first file with name.
+// Without the index, the files are scanned in the order of the central directory.
+// If the file is not found, the error wraps fs.ErrNotExist.
+func (r *Reader) Lookup(name string) (*File, error) {
+	key := r.nameKey(name)
+	if r.index != nil {
+		pos, ok := r.index.pos[key]
+		if !ok {
+			return nil, &fs.PathError{Op: "lookup", Path: name, Err: fs.ErrNotExist}
+		}
+		f, _, err := r.fileAt(pos)
+		return f, err
+	}
+
+	it := r.Iterate()
+	for it.Next() {
+		if r.nameKey(it.File().FileName) == key {
+			return it.File(), nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return nil, &fs.PathError{Op: "lookup", Path: name, Err: fs.ErrNotExist}
+}
+
+// fileAt returns the file at pos, and the position of the next file.
+// pos is the offset of the central directory header in lazy mode, or the index of Files.
+func (r *Reader) fileAt(pos int64) (*File, int64, error) {
+	if r.lazy {
+		return r.readFile(pos)
+	}
+	return r.Files[pos], pos + 1, nil
+}
+
+// files returns all files in the zip archive.
+// In lazy mode, all files are read from the central directory.
+func (r *Reader) files() ([]*File, error) {
+	if !r.lazy {
+		return r.Files, nil
+	}
+
+	files := make([]*File, 0, r.entries)
+	it := r.Iterate()
+	for it.Next() {
+		files = append(files, it.File())
+	}
+	return files, it.Err()
+}
@@ -0,0 +1,107 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestReaderLazy(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{AllowDuplicates: true})
+	if err != nil {
+		t.Fatalf("NewWriterWithOptions error=%v", err)
+	}
+	names := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		names = append(names, fmt.Sprintf("dir/file%03d.txt", i))
+	}
+	names = append(names, "dir/file000.txt") // duplicate
+	for i, name := range names {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Writer.Create error=%v", err)
+		}
+		if _, err := fmt.Fprintf(fw, "content %d", i); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	for _, lazy := range []bool{true, false} {
+		t.Run(fmt.Sprintf("lazy=%v", lazy), func(t *testing.T) {
+			zr, err := NewReaderWithOptions(bytes.NewReader(src), &ReaderOptions{Lazy: lazy})
+			if err != nil {
+				t.Fatalf("NewReaderWithOptions error=%v", err)
+			}
+			if lazy && zr.Files != nil {
+				t.Fatalf("Files size=%d, want=nil", len(zr.Files))
+			}
+			if zr.NumFiles() != len(names) {
+				t.Fatalf("NumFiles=%d, want=%d", zr.NumFiles(), len(names))
+			}
+
+			i := 0
+			it := zr.Iterate()
+			for it.Next() {
+				if it.File().FileName != names[i] {
+					t.Fatalf("File[%d] name=%q, want=%q", i, it.File().FileName, names[i])
+				}
+				i++
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("FileIterator.Err error=%v", err)
+			}
+			if i != len(names) {
+				t.Fatalf("iterated files=%d, want=%d", i, len(names))
+			}
+
+			for _, index := range []bool{false, true} {
+				if index {
+					if err := zr.BuildIndex(); err != nil {
+						t.Fatalf("Reader.BuildIndex error=%v", err)
+					}
+				}
+
+				f, err := zr.Lookup("dir/file050.txt")
+				if err != nil {
+					t.Fatalf("Reader.Lookup error=%v", err)
+				}
+				if got := readAll(t, f); got != "content 50" {
+					t.Fatalf("content=%q, want=%q", got, "content 50")
+				}
+				f, err = zr.Lookup("dir/file000.txt")
+				if err != nil {
+					t.Fatalf("Reader.Lookup error=%v", err)
+				}
+				if got := readAll(t, f); got != "content 0" {
+					t.Fatalf("duplicate content=%q, want=%q", got, "content 0")
+				}
+				if _, err := zr.Lookup("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("Reader.Lookup error=%v, want=%v", err, fs.ErrNotExist)
+				}
+			}
+		})
+	}
+
+	// limits are checked while iterating
+	zr, err := NewReaderWithOptions(bytes.NewReader(src), &ReaderOptions{Lazy: true, MaxNameLength: 10})
+	if err != nil {
+		t.Fatalf("NewReaderWithOptions error=%v", err)
+	}
+	it := zr.Iterate()
+	if it.Next() {
+		t.Fatalf("FileIterator.Next=true, want=false")
+	}
+	var lerr *LimitError
+	if !errors.As(it.Err(), &lerr) || lerr.Limit != "MaxNameLength" {
+		t.Fatalf("FileIterator.Err error=%v, want MaxNameLength", it.Err())
+	}
+}
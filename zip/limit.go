@@ -0,0 +1,158 @@
+package zip
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// LimitError represents an error that a resource limit of zip.Reader is exceeded.
+type LimitError struct {
+	Limit string // name of the limit in ReaderOptions (UncompressedSize for the declared file size)
+	Name  string // file name (empty if the limit is for the whole archive)
+	Value int64  // actual value
+	Max   int64  // limit value
+}
+
+// Error returns the error message.
+func (e *LimitError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("zip: %s exceeded: %d > %d", e.Limit, e.Value, e.Max)
+	}
+	return fmt.Sprintf("zip: %s exceeded: %q: %d > %d", e.Limit, e.Name, e.Value, e.Max)
+}
+
+// readerLimit enforces the resource limits of zip.Reader.
+// All methods accept nil receiver, which means no limits.
+// The total size is checked once against the declared sizes, and the decompressed data
+// is checked per file against its declared size, so a file can be read any number of times.
+type readerLimit struct {
+	opts ReaderOptions
+}
+
+// checkArchive checks the limits for the end of central directory record.
+func (l *readerLimit) checkArchive(enddir *endCentralDirectory) error {
+	if l == nil {
+		return nil
+	}
+	if max := l.opts.MaxEntries; max > 0 && int(enddir.numberOfEntries) > max {
+		return &LimitError{Limit: "MaxEntries", Value: int64(enddir.numberOfEntries), Max: int64(max)}
+	}
+	if max := l.opts.MaxCommentLength; max > 0 && len(enddir.comment) > max {
+		return &LimitError{Limit: "MaxCommentLength", Value: int64(len(enddir.comment)), Max: int64(max)}
+	}
+	return nil
+}
+
+// checkFile checks the limits for the central directory header.
+func (l *readerLimit) checkFile(cdir *centralDirectoryHeader, f *File) error {
+	if l == nil {
+		return nil
+	}
+	name := f.FileName
+	if max := l.opts.MaxNameLength; max > 0 && len(cdir.fileName) > max {
+		return &LimitError{Limit: "MaxNameLength", Name: name, Value: int64(len(cdir.fileName)), Max: int64(max)}
+	}
+	if max := l.opts.MaxExtraLength; max > 0 && len(cdir.extraFields) > max {
+		return &LimitError{Limit: "MaxExtraLength", Name: name, Value: int64(len(cdir.extraFields)), Max: int64(max)}
+	}
+	if max := l.opts.MaxCommentLength; max > 0 && len(cdir.comment) > max {
+		return &LimitError{Limit: "MaxCommentLength", Name: name, Value: int64(len(cdir.comment)), Max: int64(max)}
+	}
+	if max := l.opts.MaxCompressionRatio; max > 0 {
+		if ratio := compressionRatio(int64(f.UncompressedSize), int64(f.CompressedSize)); ratio > max {
+			return &LimitError{Limit: "MaxCompressionRatio", Name: name, Value: ratio, Max: max}
+		}
+	}
+	return nil
+}
+
+// checkTotal checks the limit of the total declared size of the files.
+func (l *readerLimit) checkTotal(files []*File) error {
+	if l == nil {
+		return nil
+	}
+	max := l.opts.MaxTotalUncompressedSize
+	if max <= 0 {
+		return nil
+	}
+	var total int64
+	for _, f := range files {
+		total += int64(f.UncompressedSize)
+		if total > max {
+			return &LimitError{Limit: "MaxTotalUncompressedSize", Name: f.FileName, Value: total, Max: max}
+		}
+	}
+	return nil
+}
+
+// checkOverlapping checks that the files do not overlap each other.
+func (l *readerLimit) checkOverlapping(files []*File) error {
+	if l == nil || !l.opts.RejectOverlapping {
+		return nil
+	}
+
+	sorted := make([]*File, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].offset < sorted[j].offset
+	})
+
+	// local file header has at least the fixed fields and the name
+	end := int64(-1)
+	for _, f := range sorted {
+		if f.offset < end {
+			return &LimitError{Limit: "RejectOverlapping", Name: f.FileName, Value: f.offset, Max: end}
+		}
+		end = f.offset + int64(sizeLocalFileHeader) + int64(len(f.FileName)) + int64(f.CompressedSize)
+	}
+	return nil
+}
+
+// newReader returns io.ReadCloser that checks the limits for the decompressed data.
+func (l *readerLimit) newReader(f *File, r io.ReadCloser) io.ReadCloser {
+	return &limitReader{
+		r:     r,
+		f:     f,
+		limit: l,
+	}
+}
+
+// compressionRatio returns the ratio of uncompressed size to compressed size (rounded up).
+func compressionRatio(uncompressed, compressed int64) int64 {
+	if compressed == 0 {
+		compressed = 1
+	}
+	return (uncompressed + compressed - 1) / compressed
+}
+
+// limitReader implements io.ReadCloser that checks the limits for the decompressed data.
+type limitReader struct {
+	r     io.ReadCloser
+	f     *File
+	limit *readerLimit
+	n     int64 // decompressed size
+}
+
+// Read implements the standard Read interface.
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	name := r.f.FileName
+	opts := r.limit.opts
+	if r.n > int64(r.f.UncompressedSize) {
+		return n, &LimitError{Limit: "UncompressedSize", Name: name, Value: r.n, Max: int64(r.f.UncompressedSize)}
+	}
+	if max := opts.MaxCompressionRatio; max > 0 {
+		if ratio := compressionRatio(r.n, int64(r.f.CompressedSize)); ratio > max {
+			return n, &LimitError{Limit: "MaxCompressionRatio", Name: name, Value: ratio, Max: max}
+		}
+	}
+	return n, err
+}
+
+// Close implements the standard Close interface.
+func (r *limitReader) Close() error {
+	return r.r.Close()
+}
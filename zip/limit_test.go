@@ -0,0 +1,170 @@
+package zip
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestReaderLimits(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	zw.Comment = "archive comment"
+	files := []struct {
+		name    string
+		content string
+		comment string
+	}{
+		{"small.txt", "small content", ""},
+		{"zeros.bin", strings.Repeat("\x00", 100000), "compressible"},
+		{"long-name-of-the-file.txt", "content", ""},
+	}
+	for _, file := range files {
+		fh := NewFileHeader(file.name)
+		fh.Comment = file.comment
+		fw, err := zw.CreateFromHeader(fh)
+		if err != nil {
+			t.Fatalf("Writer.CreateFromHeader error=%v", err)
+		}
+		if _, err := fw.Write([]byte(file.content)); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	tests := []struct {
+		name  string
+		opts  ReaderOptions
+		limit string // expected limit error at NewReader
+	}{
+		{"no-limit", ReaderOptions{}, ""},
+		{"entries-ok", ReaderOptions{MaxEntries: 3}, ""},
+		{"entries", ReaderOptions{MaxEntries: 2}, "MaxEntries"},
+		{"total", ReaderOptions{MaxTotalUncompressedSize: 100000}, "MaxTotalUncompressedSize"},
+		{"ratio", ReaderOptions{MaxCompressionRatio: 100}, "MaxCompressionRatio"},
+		{"name", ReaderOptions{MaxNameLength: 20}, "MaxNameLength"},
+		{"comment", ReaderOptions{MaxCommentLength: 12}, "MaxCommentLength"},
+		{"overlapping-ok", ReaderOptions{RejectOverlapping: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			zr, err := NewReaderWithOptions(bytes.NewReader(src), &opts)
+			if tt.limit != "" {
+				var lerr *LimitError
+				if !errors.As(err, &lerr) {
+					t.Fatalf("NewReaderWithOptions error=%v, want LimitError", err)
+				}
+				if lerr.Limit != tt.limit {
+					t.Errorf("LimitError.Limit=%q, want=%q", lerr.Limit, tt.limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewReaderWithOptions error=%v", err)
+			}
+			for i, f := range zr.Files {
+				if got := readAll(t, f); got != files[i].content {
+					t.Errorf("%s: content size=%d, want=%d", f.FileName, len(got), len(files[i].content))
+				}
+			}
+		})
+	}
+
+	// central directory header offsets
+	cdirs := make([]int, 0)
+	for i := 0; i+4 <= len(src); i++ {
+		if string(src[i:i+4]) == signCentralDirectoryHeader {
+			cdirs = append(cdirs, i)
+		}
+	}
+
+	t.Run("overlapping", func(t *testing.T) {
+		data := append([]byte{}, src...)
+		binary.LittleEndian.PutUint32(data[cdirs[2]+42:], 0)
+
+		_, err := NewReaderWithOptions(bytes.NewReader(data), &ReaderOptions{RejectOverlapping: true})
+		var lerr *LimitError
+		if !errors.As(err, &lerr) || lerr.Limit != "RejectOverlapping" {
+			t.Fatalf("NewReaderWithOptions error=%v, want RejectOverlapping", err)
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		// declared uncompressed size is smaller than the actual size
+		data := append([]byte{}, src...)
+		binary.LittleEndian.PutUint32(data[cdirs[1]+24:], 1000)
+
+		zr, err := NewReaderWithOptions(bytes.NewReader(data), &ReaderOptions{MaxCompressionRatio: 100})
+		if err != nil {
+			t.Fatalf("NewReaderWithOptions error=%v", err)
+		}
+		r, err := zr.Files[1].Open()
+		if err != nil {
+			t.Fatalf("Open error=%v", err)
+		}
+		defer r.Close()
+
+		_, err = io.Copy(io.Discard, r)
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Fatalf("read error=%v, want LimitError", err)
+		}
+		if lerr.Name != "zeros.bin" {
+			t.Errorf("LimitError.Name=%q, want=%q", lerr.Name, "zeros.bin")
+		}
+	})
+}
+
+func TestReaderLimitsReread(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	content := strings.Repeat("0123456789", 100)
+	fw, err := zw.Create("data.txt")
+	if err != nil {
+		t.Fatalf("Writer.Create error=%v", err)
+	}
+	if _, err := fw.Write([]byte(content)); err != nil {
+		t.Fatalf("FileWriter.Write error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+
+	// the file is read more than the total size, but each read is within the declared size
+	zr, err := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), &ReaderOptions{MaxTotalUncompressedSize: 1500})
+	if err != nil {
+		t.Fatalf("NewReaderWithOptions error=%v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if got := readAll(t, zr.Files[0]); got != content {
+			t.Fatalf("read %d: content size=%d, want=%d", i, len(got), len(content))
+		}
+	}
+	report, err := zr.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Reader.Verify error=%v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("Reader.Verify report=%+v", report)
+	}
+	if err := zr.Extract(t.TempDir()); err != nil {
+		t.Fatalf("Reader.Extract error=%v", err)
+	}
+}
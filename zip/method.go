@@ -2,8 +2,6 @@ package zip
 
 import (
 	"compress/flate"
-	"errors"
-	"fmt"
 	"io"
 )
 
@@ -27,19 +25,21 @@ func methodFactory(method uint16) (MethodType, error) {
 	case methodStoreID:
 		return &MethodStore{}, nil
 	case methodDeflatedID:
-		return &MethodDeflated{DefaultCompression}, nil
+		return &MethodDeflated{Compression: DefaultCompression}, nil
 	}
-	return nil, errors.New("unsupport compression method")
+	return nil, wrapError(ErrAlgorithm, "method=%d", method)
 }
 
 // CompressionType represents a compression level.
 type CompressionType int
 
 const (
-	DefaultCompression   CompressionType = iota // default level compression
-	MaximumCompression                          // maximum compression
-	FastCompression                             // fast compression
-	SuperFastCompression                        // super fast compression
+	DefaultCompression     CompressionType = iota // default level compression
+	MaximumCompression                            // maximum compression
+	FastCompression                               // fast compression
+	SuperFastCompression                          // super fast compression
+	HuffmanOnlyCompression                        // Huffman encoding only
+	NoCompression                                 // deflate format without compression
 )
 
 // MethodStore is a compression method for storing data.
@@ -74,6 +74,16 @@ func (m MethodStore) newDecompressor(r io.Reader) (io.ReadCloser, error) {
 // MethodDeflated is a compression method for deflate.
 type MethodDeflated struct {
 	Compression CompressionType
+
+	// Level is the deflate level from 1 (best speed) to 9 (best compression).
+	// If 0, Compression is used. The level is not stored in the header,
+	// and it is written as the nearest Compression in the flags.
+	Level int
+
+	// Dictionary is the preset dictionary for families of small similar files.
+	// The dictionary is not stored in the zip archive, so it is not readable by other tools.
+	// To read the file, set the same dictionary to the File's MethodDeflated before Open.
+	Dictionary []byte
 }
 
 // ID returns a compression method's ID.
@@ -98,39 +108,69 @@ func (m *MethodDeflated) set(flag uint16) error {
 
 // get returns method options in zip header's flag format.
 func (m MethodDeflated) get() uint16 {
-	switch m.Compression {
+	switch m.compression() {
 	case DefaultCompression:
 		return 0x00 << 1
 	case MaximumCompression:
 		return 0x01 << 1
 	case FastCompression:
 		return 0x02 << 1
-	case SuperFastCompression:
+	case SuperFastCompression, HuffmanOnlyCompression, NoCompression:
 		return 0x03 << 1
 	}
 	return 0 // never reach
 }
 
-// newCompressor returns a compressor.
-func (m MethodDeflated) newCompressor(w io.Writer) (io.WriteCloser, error) {
-	var level int
+// compression returns Compression of the nearest flags for Level, or Compression if Level is 0.
+// The mapping is the same as Info-ZIP.
+func (m MethodDeflated) compression() CompressionType {
+	switch {
+	case m.Level == 0:
+		return m.Compression
+	case m.Level >= 8:
+		return MaximumCompression
+	case m.Level == 2:
+		return FastCompression
+	case m.Level == 1:
+		return SuperFastCompression
+	}
+	return DefaultCompression
+}
+
+// level returns the level of compress/flate.
+func (m MethodDeflated) level() (int, error) {
+	if m.Level != 0 {
+		if m.Level < flate.BestSpeed || m.Level > flate.BestCompression {
+			return 0, wrapError(ErrAlgorithm, "deflate level %d", m.Level)
+		}
+		return m.Level, nil
+	}
+
 	switch m.Compression {
 	case DefaultCompression:
-		level = flate.DefaultCompression
+		return flate.DefaultCompression, nil
 	case MaximumCompression:
-		level = flate.BestCompression
+		return flate.BestCompression, nil
 	case FastCompression:
-		level = flate.BestSpeed
-	case SuperFastCompression:
-		level = flate.HuffmanOnly // Super Fast
-	default:
-		return nil, fmt.Errorf("unsupport compression level: %#v", m.Compression)
+		return flate.BestSpeed, nil
+	case SuperFastCompression, HuffmanOnlyCompression:
+		return flate.HuffmanOnly, nil
+	case NoCompression:
+		return flate.NoCompression, nil
 	}
+	return 0, wrapError(ErrAlgorithm, "compression level %#v", m.Compression)
+}
 
-	return flate.NewWriter(w, level)
+// newCompressor returns a compressor.
+func (m MethodDeflated) newCompressor(w io.Writer) (io.WriteCloser, error) {
+	level, err := m.level()
+	if err != nil {
+		return nil, err
+	}
+	return newFlateWriter(w, level, m.Dictionary)
 }
 
 // newDecompressor returns a decompressor.
 func (m MethodDeflated) newDecompressor(r io.Reader) (io.ReadCloser, error) {
-	return flate.NewReader(r), nil
+	return newFlateReader(r, m.Dictionary)
 }
@@ -0,0 +1,140 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestMethodDeflated(t *testing.T) {
+	content := strings.Repeat("The quick brown fox jumps over the lazy dog.\n", 100)
+	tests := []struct {
+		name   string
+		method *MethodDeflated
+		want   CompressionType // compression read from the flags
+	}{
+		{"default", &MethodDeflated{Compression: DefaultCompression}, DefaultCompression},
+		{"huffman-only", &MethodDeflated{Compression: HuffmanOnlyCompression}, SuperFastCompression},
+		{"no-compression", &MethodDeflated{Compression: NoCompression}, SuperFastCompression},
+		{"level-1", &MethodDeflated{Level: 1}, SuperFastCompression},
+		{"level-2", &MethodDeflated{Level: 2}, FastCompression},
+		{"level-3", &MethodDeflated{Level: 3}, DefaultCompression},
+		{"level-7", &MethodDeflated{Level: 7}, DefaultCompression},
+		{"level-8", &MethodDeflated{Level: 8}, MaximumCompression},
+		{"level-9", &MethodDeflated{Level: 9, Compression: FastCompression}, MaximumCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			fh := NewFileHeader("a.txt")
+			fh.Method = tt.method
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if _, err := io.WriteString(fw, content); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+			if tt.name == "no-compression" && fh.CompressedSize <= fh.UncompressedSize {
+				t.Fatalf("CompressedSize=%d, want>%d", fh.CompressedSize, fh.UncompressedSize)
+			}
+
+			zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			f := zr.Files[0]
+			if got := f.Method.(*MethodDeflated).Compression; got != tt.want {
+				t.Fatalf("Compression=%d, want=%d", got, tt.want)
+			}
+			if got := readAll(t, f); got != content {
+				t.Fatalf("content size=%d, want=%d", len(got), len(content))
+			}
+		})
+	}
+
+	for _, m := range []*MethodDeflated{{Level: -1}, {Level: 10}, {Compression: 100}} {
+		zw, err := NewWriter(buffer.NewWriter(new(buffer.Buffer)))
+		if err != nil {
+			t.Fatalf("NewWriter error=%v", err)
+		}
+		fh := NewFileHeader("a.txt")
+		fh.Method = m
+		fw, err := zw.CreateFromHeader(fh)
+		if err != nil {
+			t.Fatalf("Writer.CreateFromHeader error=%v", err)
+		}
+		if _, err := io.WriteString(fw, content); !errors.Is(err, ErrAlgorithm) {
+			t.Fatalf("FileWriter.Write %+v error=%v, want=%v", m, err, ErrAlgorithm)
+		}
+	}
+}
+
+func TestMethodDeflatedDictionary(t *testing.T) {
+	dict := []byte(`{"id": 0, "name": "", "email": "@example.com", "active": true}`)
+	write := func(dict []byte) ([]byte, uint32) {
+		buf := new(buffer.Buffer)
+		zw, err := NewWriter(buffer.NewWriter(buf))
+		if err != nil {
+			t.Fatalf("NewWriter error=%v", err)
+		}
+		var size uint32
+		for i := 0; i < 10; i++ {
+			fh := NewFileHeader(fmt.Sprintf("user%d.json", i))
+			fh.Method = &MethodDeflated{Level: 9, Dictionary: dict}
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			fmt.Fprintf(fw, `{"id": %d, "name": "user%d", "email": "user%d@example.com", "active": true}`, i, i, i)
+			if err := fw.Close(); err != nil {
+				t.Fatalf("FileWriter.Close error=%v", err)
+			}
+			size += fh.CompressedSize
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Writer.Close error=%v", err)
+		}
+		return buf.Bytes(), size
+	}
+
+	_, plain := write(nil)
+	data, size := write(dict)
+	if size >= plain {
+		t.Fatalf("compressed size with dictionary=%d, want<%d", size, plain)
+	}
+
+	zr, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	for i, f := range zr.Files {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("File.Open error=%v", err)
+		}
+		if _, err := io.ReadAll(r); err == nil {
+			t.Fatalf("reading without dictionary error=nil")
+		}
+		r.Close()
+
+		f.Method.(*MethodDeflated).Dictionary = dict
+		want := fmt.Sprintf(`{"id": %d, "name": "user%d", "email": "user%d@example.com", "active": true}`, i, i, i)
+		if got := readAll(t, f); got != want {
+			t.Fatalf("content=%q, want=%q", got, want)
+		}
+	}
+}
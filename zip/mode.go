@@ -0,0 +1,231 @@
+package zip
+
+import (
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	unixTypeMask  uint32 = 0170000 // S_IFMT
+	unixFIFO      uint32 = 0010000 // S_IFIFO
+	unixCharDev   uint32 = 0020000 // S_IFCHR
+	unixDirectory uint32 = 0040000 // S_IFDIR
+	unixBlockDev  uint32 = 0060000 // S_IFBLK
+	unixRegular   uint32 = 0100000 // S_IFREG
+	unixSymlink   uint32 = 0120000 // S_IFLNK
+	unixSocket    uint32 = 0140000 // S_IFSOCK
+	unixSetuid    uint32 = 0004000 // S_ISUID
+	unixSetgid    uint32 = 0002000 // S_ISGID
+	unixSticky    uint32 = 0001000 // S_ISVTX
+
+	dosReadOnly  uint32 = 0x01 // FILE_ATTRIBUTE_READONLY
+	dosHidden    uint32 = 0x02 // FILE_ATTRIBUTE_HIDDEN
+	dosDirectory uint32 = 0x10 // FILE_ATTRIBUTE_DIRECTORY
+	dosArchive   uint32 = 0x20 // FILE_ATTRIBUTE_ARCHIVE
+)
+
+// isUnixOS returns whether the external file attributes are Unix style.
+func (fh *FileHeader) isUnixOS() bool {
+	switch fh.GenerateOS {
+	case OS_UNIX, OS_OSX:
+		return true
+	}
+	return false
+}
+
+// Mode returns the permission and mode bits for the FileHeader.
+// The attributes are interpreted according to GenerateOS.
+// The DOS hidden attribute has no fs.FileMode equivalent and is ignored.
+func (fh *FileHeader) Mode() fs.FileMode {
+	var mode fs.FileMode
+	if fh.isUnixOS() {
+		mode = fileModeFromUnix(fh.ExternalFileAttr >> 16)
+	} else {
+		mode = fileModeFromDos(fh.ExternalFileAttr)
+	}
+
+	if strings.HasSuffix(fh.FileName, "/") {
+		mode |= fs.ModeDir
+	}
+	return mode
+}
+
+// SetMode changes the permission and mode bits for the FileHeader.
+// If the mode can not be represented by DOS attributes (e.g. 0755 or a symbolic link),
+// GenerateOS is changed to OS_UNIX, so the result does not depend on the order of the calls.
+// Unix mode bits are stored only if GenerateOS is a Unix style OS.
+// DOS attributes are always stored, and the hidden attribute is preserved.
+func (fh *FileHeader) SetMode(mode fs.FileMode) {
+	if !fh.isUnixOS() && fileModeFromDos(fileModeToDos(mode)) != mode {
+		fh.GenerateOS = OS_UNIX
+	}
+	attr := fh.ExternalFileAttr & dosHidden
+	attr |= fileModeToDos(mode)
+	if fh.isUnixOS() {
+		attr |= fileModeToUnix(mode) << 16
+	}
+	fh.ExternalFileAttr = attr
+}
+
+// IsSymlink returns whether the FileHeader represents a symbolic link.
+// Only Unix style attributes can represent symbolic links.
+func (fh *FileHeader) IsSymlink() bool {
+	return fh.isUnixOS() && (fh.ExternalFileAttr>>16)&unixTypeMask == unixSymlink
+}
+
+// FileInfo returns fs.FileInfo for the FileHeader.
+func (fh *FileHeader) FileInfo() fs.FileInfo {
+	return headerFileInfo{fh}
+}
+
+// FileInfoHeader creates a new FileHeader from fs.FileInfo.
+// Because fs.FileInfo's Name method returns only the base name,
+// it may be necessary to modify the FileName of the returned header.
+// Directories are stored with the Store method.
+func FileInfoHeader(fi fs.FileInfo) (*FileHeader, error) {
+	size := fi.Size()
+	if size < 0 || size > math.MaxUint32 {
+		return nil, wrapError(ErrUnsupported, "file size %d is too large (ZIP64)", size)
+	}
+
+	name := fi.Name()
+	if fi.IsDir() {
+		name += "/"
+	}
+
+	fh := NewFileHeader(name)
+	fh.GenerateOS = OS_UNIX
+	fh.ModifiedTime = fi.ModTime()
+	fh.UncompressedSize = uint32(size)
+	fh.SetMode(fi.Mode())
+	if fi.IsDir() {
+		fh.Method = &MethodStore{}
+	}
+	return fh, nil
+}
+
+// headerFileInfo implements fs.FileInfo for FileHeader.
+type headerFileInfo struct {
+	fh *FileHeader
+}
+
+// Name returns the base name of the file.
+func (fi headerFileInfo) Name() string {
+	return path.Base(strings.TrimSuffix(fi.fh.FileName, "/"))
+}
+
+// Size returns the uncompressed size of the file.
+func (fi headerFileInfo) Size() int64 {
+	return int64(fi.fh.UncompressedSize)
+}
+
+// Mode returns the file mode bits.
+func (fi headerFileInfo) Mode() fs.FileMode {
+	return fi.fh.Mode()
+}
+
+// ModTime returns the modification time.
+func (fi headerFileInfo) ModTime() time.Time {
+	return fi.fh.ModifiedTime
+}
+
+// IsDir returns whether the file is a directory.
+func (fi headerFileInfo) IsDir() bool {
+	return fi.Mode().IsDir()
+}
+
+// Sys returns the FileHeader.
+func (fi headerFileInfo) Sys() any {
+	return fi.fh
+}
+
+// fileModeToUnix converts fs.FileMode to Unix st_mode bits.
+func fileModeToUnix(mode fs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	switch mode & fs.ModeType {
+	case fs.ModeDir:
+		m |= unixDirectory
+	case fs.ModeSymlink:
+		m |= unixSymlink
+	case fs.ModeNamedPipe:
+		m |= unixFIFO
+	case fs.ModeSocket:
+		m |= unixSocket
+	case fs.ModeDevice | fs.ModeCharDevice:
+		m |= unixCharDev
+	case fs.ModeDevice:
+		m |= unixBlockDev
+	default:
+		m |= unixRegular
+	}
+	if mode&fs.ModeSetuid != 0 {
+		m |= unixSetuid
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= unixSetgid
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= unixSticky
+	}
+	return m
+}
+
+// fileModeFromUnix converts Unix st_mode bits to fs.FileMode.
+func fileModeFromUnix(m uint32) fs.FileMode {
+	mode := fs.FileMode(m & 0777)
+	switch m & unixTypeMask {
+	case unixDirectory:
+		mode |= fs.ModeDir
+	case unixSymlink:
+		mode |= fs.ModeSymlink
+	case unixFIFO:
+		mode |= fs.ModeNamedPipe
+	case unixSocket:
+		mode |= fs.ModeSocket
+	case unixCharDev:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case unixBlockDev:
+		mode |= fs.ModeDevice
+	}
+	if m&unixSetuid != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if m&unixSetgid != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if m&unixSticky != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
+
+// fileModeToDos converts fs.FileMode to DOS attribute bits.
+func fileModeToDos(mode fs.FileMode) uint32 {
+	var attr uint32
+	if mode.IsDir() {
+		attr |= dosDirectory
+	} else {
+		attr |= dosArchive
+	}
+	if mode&0200 == 0 {
+		attr |= dosReadOnly
+	}
+	return attr
+}
+
+// fileModeFromDos converts DOS attribute bits to fs.FileMode.
+func fileModeFromDos(attr uint32) fs.FileMode {
+	var mode fs.FileMode
+	if attr&dosDirectory != 0 {
+		mode = fs.ModeDir | 0777
+	} else {
+		mode = 0666
+	}
+	if attr&dosReadOnly != 0 {
+		mode &^= 0222
+	}
+	return mode
+}
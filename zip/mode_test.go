@@ -0,0 +1,130 @@
+package zip
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFileHeaderMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		os     OSType
+		attr   uint32
+		mode   fs.FileMode
+		setter bool // test SetMode
+	}{
+		{"unix.txt", OS_UNIX, 0100644<<16 | 0x20, 0644, true},
+		{"exec", OS_UNIX, 0104755<<16 | 0x20, fs.ModeSetuid | 0755, true},
+		{"readonly", OS_UNIX, 0100444<<16 | 0x21, 0444, true},
+		{"dir/", OS_UNIX, 0040755<<16 | 0x10, fs.ModeDir | 0755, true},
+		{"link", OS_OSX, 0120777<<16 | 0x20, fs.ModeSymlink | 0777, true},
+		{"dos.txt", OS_MSDOS, 0x20, 0666, true},
+		{"dos-readonly.txt", OS_MSDOS, 0x21, 0444, true},
+		{"hidden.txt", OS_NTFS, 0x22, 0666, false},
+		{"dosdir/", OS_MSDOS, 0x10, fs.ModeDir | 0777, true},
+		{"nodir/", OS_MSDOS, 0x00, fs.ModeDir | 0666, false},
+	}
+
+	for _, tt := range tests {
+		fh := NewFileHeader(tt.name)
+		fh.GenerateOS = tt.os
+		fh.ExternalFileAttr = tt.attr
+
+		if mode := fh.Mode(); mode != tt.mode {
+			t.Errorf("%s: Mode=%v, want=%v", tt.name, mode, tt.mode)
+		}
+		if !tt.setter {
+			continue
+		}
+
+		fh.ExternalFileAttr = 0
+		fh.SetMode(tt.mode)
+		if fh.ExternalFileAttr != tt.attr {
+			t.Errorf("%s: SetMode attr=%#x, want=%#x", tt.name, fh.ExternalFileAttr, tt.attr)
+		}
+	}
+}
+
+func TestFileHeaderSetModeOS(t *testing.T) {
+	tests := []struct {
+		name string
+		mode fs.FileMode
+		os   OSType
+	}{
+		{"file.txt", 0666, OS_MSDOS},
+		{"readonly.txt", 0444, OS_MSDOS},
+		{"dir/", fs.ModeDir | 0777, OS_MSDOS},
+		{"exec", 0755, OS_UNIX},
+		{"private.txt", 0600, OS_UNIX},
+		{"link", fs.ModeSymlink | 0777, OS_UNIX},
+		{"private/", fs.ModeDir | 0700, OS_UNIX},
+	}
+
+	for _, tt := range tests {
+		// GenerateOS is left as OS_MSDOS
+		fh := NewFileHeader(tt.name)
+		fh.SetMode(tt.mode)
+		if fh.GenerateOS != tt.os {
+			t.Errorf("%s: GenerateOS=%v, want=%v", tt.name, fh.GenerateOS, tt.os)
+		}
+		if mode := fh.Mode(); mode != tt.mode {
+			t.Errorf("%s: Mode=%v, want=%v", tt.name, mode, tt.mode)
+		}
+		if tt.mode&fs.ModeSymlink != 0 && !fh.IsSymlink() {
+			t.Errorf("%s: IsSymlink=false, want=true", tt.name)
+		}
+	}
+}
+
+func TestFileInfoHeader(t *testing.T) {
+	mtime := time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"dir/file.txt": {Data: []byte("content"), Mode: 0640, ModTime: mtime},
+		"dir":          {Mode: fs.ModeDir | 0750, ModTime: mtime},
+	}
+
+	tests := []struct {
+		path string
+		name string
+	}{
+		{"dir/file.txt", "file.txt"},
+		{"dir", "dir/"},
+	}
+
+	for _, tt := range tests {
+		fi, err := fs.Stat(fsys, tt.path)
+		if err != nil {
+			t.Fatalf("fs.Stat error=%v", err)
+		}
+
+		fh, err := FileInfoHeader(fi)
+		if err != nil {
+			t.Fatalf("FileInfoHeader error=%v", err)
+		}
+		if fh.FileName != tt.name {
+			t.Errorf("FileName=%q, want=%q", fh.FileName, tt.name)
+		}
+
+		info := fh.FileInfo()
+		if info.Name() != fi.Name() {
+			t.Errorf("%s: Name=%q, want=%q", tt.path, info.Name(), fi.Name())
+		}
+		if info.Size() != fi.Size() {
+			t.Errorf("%s: Size=%d, want=%d", tt.path, info.Size(), fi.Size())
+		}
+		if info.Mode() != fi.Mode() {
+			t.Errorf("%s: Mode=%v, want=%v", tt.path, info.Mode(), fi.Mode())
+		}
+		if info.IsDir() != fi.IsDir() {
+			t.Errorf("%s: IsDir=%v, want=%v", tt.path, info.IsDir(), fi.IsDir())
+		}
+		if !info.ModTime().Equal(fi.ModTime()) {
+			t.Errorf("%s: ModTime=%v, want=%v", tt.path, info.ModTime(), fi.ModTime())
+		}
+		if info.Sys() != fh {
+			t.Errorf("%s: Sys=%v, want=%v", tt.path, info.Sys(), fh)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package zip
+
+import (
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// nameSet tracks the file names written to zip.Writer,
+// and detects the names that conflict with the names written so far.
+type nameSet struct {
+	entries map[string]*nameEntry // entry by normalized name
+	fold    cases.Caser
+}
+
+// nameEntry represents a file or a directory in nameSet.
+type nameEntry struct {
+	name     string // name without the trailing slash
+	dir      bool   // directory
+	explicit bool   // written as an entry (false if it is only a parent of the other entries)
+}
+
+// newNameSet returns empty nameSet.
+func newNameSet() *nameSet {
+	return &nameSet{
+		entries: make(map[string]*nameEntry),
+		fold:    cases.Fold(),
+	}
+}
+
+// key returns the normalized name (NFC and case folding).
+func (s *nameSet) key(name string) string {
+	return s.fold.String(norm.NFC.String(name))
+}
+
+// check checks the name against the names added so far, and does not add the name.
+func (s *nameSet) check(name string) error {
+	_, _, err := s.resolve(name)
+	return err
+}
+
+// add adds the name. It returns an error and does not add the name if
+// the name is already written, conflicts with a file or a directory,
+// or collides with the other name after normalization.
+func (s *nameSet) add(name string) error {
+	entry, parents, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		s.entries[s.key(p)] = &nameEntry{name: p, dir: true}
+	}
+	s.entries[s.key(entry.name)] = entry
+	return nil
+}
+
+// resolve returns the entry of the name and the parent directories to be added.
+// It returns an error if the name conflicts with the names added so far.
+func (s *nameSet) resolve(name string) (*nameEntry, []string, error) {
+	dir := strings.HasSuffix(name, "/")
+	base := strings.TrimSuffix(name, "/")
+
+	key := s.key(base)
+	if e, ok := s.entries[key]; ok {
+		switch {
+		case e.name != base:
+			return nil, nil, wrapError(ErrDuplicate, "%q collides with %q after normalization", name, e.name)
+		case e.dir != dir && e.dir:
+			return nil, nil, wrapError(ErrDuplicate, "file %q conflicts with directory %q", name, e.name)
+		case e.dir != dir:
+			return nil, nil, wrapError(ErrDuplicate, "directory %q conflicts with file %q", name, e.name)
+		case e.explicit:
+			return nil, nil, wrapError(ErrDuplicate, "%q is already written", name)
+		}
+	}
+
+	parents := make([]string, 0)
+	for p := path.Dir(base); p != "." && p != "/"; p = path.Dir(p) {
+		pkey := s.key(p)
+		if e, ok := s.entries[pkey]; ok {
+			if e.name != p {
+				return nil, nil, wrapError(ErrDuplicate, "directory of %q collides with %q after normalization", name, e.name)
+			}
+			if !e.dir {
+				return nil, nil, wrapError(ErrDuplicate, "%q is under file %q", name, e.name)
+			}
+			// the ancestors are already added
+			break
+		}
+		parents = append(parents, p)
+	}
+	return &nameEntry{name: base, dir: dir, explicit: true}, parents, nil
+}
@@ -0,0 +1,116 @@
+package zip
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestWriterNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		ok    bool // last file is accepted
+	}{
+		{"unique", []string{"a.txt", "b.txt"}, true},
+		{"duplicate", []string{"a.txt", "a.txt"}, false},
+		{"duplicate-directory", []string{"a/", "a/"}, false},
+		{"implicit-directory", []string{"a/b.txt", "a/"}, true},
+		{"directory-first", []string{"a/", "a/b.txt"}, true},
+		{"file-then-child", []string{"a", "a/b"}, false},
+		{"child-then-file", []string{"a/b", "a"}, false},
+		{"file-then-directory", []string{"a", "a/"}, false},
+		{"directory-then-file", []string{"a/", "a"}, false},
+		{"deep-conflict", []string{"a/b/c.txt", "a/b"}, false},
+		{"case", []string{"README", "readme"}, false},
+		{"case-directory", []string{"Dir/a.txt", "dir/b.txt"}, false},
+		{"unicode", []string{"caf\u00e9.txt", "cafe\u0301.txt"}, false},
+		{"similar", []string{"a.txt", "a.txt.bak"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, allow := range []bool{false, true} {
+				zw, err := NewWriterWithOptions(buffer.NewWriter(new(buffer.Buffer)), &WriterOptions{AllowDuplicates: allow})
+				if err != nil {
+					t.Fatalf("NewWriterWithOptions error=%v", err)
+				}
+				last := len(tt.files) - 1
+				for _, name := range tt.files[:last] {
+					if _, err := zw.Create(name); err != nil {
+						t.Fatalf("Writer.Create(%q) error=%v", name, err)
+					}
+				}
+
+				// Copy is checked as well
+				fh := NewFileHeader(tt.files[last])
+				if strings.HasSuffix(fh.FileName, "/") {
+					fh.Method = &MethodStore{}
+				}
+				err = zw.CopyFromReader(fh, strings.NewReader(""))
+				switch {
+				case (tt.ok || allow) && err != nil:
+					t.Fatalf("Writer.CopyFromReader(%q) allow=%v error=%v", fh.FileName, allow, err)
+				case !(tt.ok || allow) && !errors.Is(err, ErrDuplicate):
+					t.Fatalf("Writer.CopyFromReader(%q) error=%v, want=%v", fh.FileName, err, ErrDuplicate)
+				}
+
+				if _, err := zw.Create(tt.files[last]); !allow && !errors.Is(err, ErrDuplicate) {
+					t.Fatalf("Writer.Create(%q) error=%v, want=%v", tt.files[last], err, ErrDuplicate)
+				}
+				if err := zw.Close(); err != nil {
+					t.Fatalf("Writer.Close error=%v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriterNamesRetry(t *testing.T) {
+	zw, err := NewWriter(buffer.NewWriter(new(buffer.Buffer)))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+
+	// the name of a failed entry is not registered
+	invalid := func(name string) *FileHeader {
+		fh := NewFileHeader(name)
+		fh.Comment = strings.Repeat("x", 0x10000)
+		return fh
+	}
+	if _, err := zw.CreateFromHeader(invalid("a.txt")); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("Writer.CreateFromHeader error=%v, want=%v", err, fs.ErrInvalid)
+	}
+	if _, err := zw.Create("a.txt"); err != nil {
+		t.Fatalf("Writer.Create retry error=%v", err)
+	}
+	if err := zw.CopyFromReader(invalid("b.txt"), strings.NewReader("")); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("Writer.CopyFromReader error=%v, want=%v", err, fs.ErrInvalid)
+	}
+	if err := zw.CopyFromReader(NewFileHeader("b.txt"), strings.NewReader("")); err != nil {
+		t.Fatalf("Writer.CopyFromReader retry error=%v", err)
+	}
+	if _, err := zw.Create("b.txt"); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Writer.Create error=%v, want=%v", err, ErrDuplicate)
+	}
+
+	// the name is registered after the data is written
+	fh := NewFileHeader("c.txt")
+	fh.Method = &MethodDeflated{Level: 99}
+	fw, err := zw.CreateFromHeader(fh)
+	if err != nil {
+		t.Fatalf("Writer.CreateFromHeader error=%v", err)
+	}
+	if err := fw.Close(); !errors.Is(err, ErrAlgorithm) {
+		t.Fatalf("FileWriter.Close error=%v, want=%v", err, ErrAlgorithm)
+	}
+	if _, err := zw.Create("c.txt"); err != nil {
+		t.Fatalf("Writer.Create retry error=%v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+}
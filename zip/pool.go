@@ -0,0 +1,95 @@
+package zip
+
+import (
+	"compress/flate"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// flateWriterPools is the pools of flate.Writer for each level (flate.HuffmanOnly to flate.BestCompression).
+var flateWriterPools [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+
+// flateReaderPool is the pool of flate readers.
+var flateReaderPool sync.Pool
+
+// newFlateWriter returns a flate writer from the pool.
+// The writer is returned to the pool when it is closed.
+// Writers with a dictionary are not pooled, because Reset keeps the dictionary.
+func newFlateWriter(w io.Writer, level int, dict []byte) (io.WriteCloser, error) {
+	if dict != nil {
+		return flate.NewWriterDict(w, level, dict)
+	}
+
+	pool := &flateWriterPools[level-flate.HuffmanOnly]
+	if fw, ok := pool.Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return &pooledWriter{fw: fw, pool: pool}, nil
+	}
+	fw, err := flate.NewWriter(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledWriter{fw: fw, pool: pool}, nil
+}
+
+// pooledWriter implements io.WriteCloser that returns flate.Writer to the pool on Close.
+type pooledWriter struct {
+	fw   *flate.Writer
+	pool *sync.Pool
+}
+
+// Write implements the standard Write interface.
+func (w *pooledWriter) Write(p []byte) (int, error) {
+	if w.fw == nil {
+		return 0, fs.ErrClosed
+	}
+	return w.fw.Write(p)
+}
+
+// Close flushes the data and returns flate.Writer to the pool.
+func (w *pooledWriter) Close() error {
+	if w.fw == nil {
+		return fs.ErrClosed
+	}
+	err := w.fw.Close()
+	w.pool.Put(w.fw)
+	w.fw = nil
+	return err
+}
+
+// newFlateReader returns a flate reader from the pool.
+// The reader is returned to the pool when it is closed.
+func newFlateReader(r io.Reader, dict []byte) (io.ReadCloser, error) {
+	if fr, ok := flateReaderPool.Get().(io.ReadCloser); ok {
+		if err := fr.(flate.Resetter).Reset(r, dict); err != nil {
+			return nil, err
+		}
+		return &pooledReader{fr: fr}, nil
+	}
+	return &pooledReader{fr: flate.NewReaderDict(r, dict)}, nil
+}
+
+// pooledReader implements io.ReadCloser that returns the flate reader to the pool on Close.
+type pooledReader struct {
+	fr io.ReadCloser
+}
+
+// Read implements the standard Read interface.
+func (r *pooledReader) Read(p []byte) (int, error) {
+	if r.fr == nil {
+		return 0, fs.ErrClosed
+	}
+	return r.fr.Read(p)
+}
+
+// Close returns the flate reader to the pool.
+func (r *pooledReader) Close() error {
+	if r.fr == nil {
+		return fs.ErrClosed
+	}
+	err := r.fr.Close()
+	flateReaderPool.Put(r.fr)
+	r.fr = nil
+	return err
+}
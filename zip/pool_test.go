@@ -0,0 +1,117 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+// writeSmallFiles writes a zip archive of n small files.
+func writeSmallFiles(tb testing.TB, n int) []byte {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{AllowDuplicates: true})
+	if err != nil {
+		tb.Fatalf("NewWriterWithOptions error=%v", err)
+	}
+	for i := 0; i < n; i++ {
+		fw, err := zw.Create(fmt.Sprintf("file%d.txt", i))
+		if err != nil {
+			tb.Fatalf("Writer.Create error=%v", err)
+		}
+		if _, err := fmt.Fprintf(fw, "small file %d, small file %d", i, i); err != nil {
+			tb.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tb.Fatalf("Writer.Close error=%v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFlatePool(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := writeSmallFiles(t, 100)
+			zr, err := NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Errorf("NewReader error=%v", err)
+				return
+			}
+			for i, f := range zr.Files {
+				r, err := f.Open()
+				if err != nil {
+					t.Errorf("File.Open error=%v", err)
+					return
+				}
+				got, err := io.ReadAll(r)
+				r.Close()
+				if want := fmt.Sprintf("small file %d, small file %d", i, i); err != nil || string(got) != want {
+					t.Errorf("content=%q error=%v, want=%q", got, err, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// closed writer and reader are not reused
+	w, err := MethodDeflated{}.newCompressor(io.Discard)
+	if err != nil {
+		t.Fatalf("newCompressor error=%v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error=%v", err)
+	}
+	if _, err := w.Write([]byte("a")); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("Write error=%v, want=%v", err, fs.ErrClosed)
+	}
+	if err := w.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("Close error=%v, want=%v", err, fs.ErrClosed)
+	}
+	r, err := MethodDeflated{}.newDecompressor(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("newDecompressor error=%v", err)
+	}
+	r.Close()
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("Read error=%v, want=%v", err, fs.ErrClosed)
+	}
+}
+
+func BenchmarkWriteSmallFiles(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeSmallFiles(b, 1000)
+	}
+}
+
+func BenchmarkReadSmallFiles(b *testing.B) {
+	data := writeSmallFiles(b, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zr, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatalf("NewReader error=%v", err)
+		}
+		for _, f := range zr.Files {
+			r, err := f.Open()
+			if err != nil {
+				b.Fatalf("File.Open error=%v", err)
+			}
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				b.Fatalf("io.Copy error=%v", err)
+			}
+			r.Close()
+		}
+	}
+}
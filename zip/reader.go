@@ -4,22 +4,75 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"io/fs"
 	"math"
+	"strings"
+	"time"
 )
 
 // Reader reads a zip file.
 type Reader struct {
-	r io.ReadSeeker
+	r         io.ReadSeeker
+	starts    []int64        // start offset of each volume in r
+	dirOffset int64          // offset of the central directory in r
+	limit     *readerLimit   // resource limits (nil if no limits)
+	loc       *time.Location // location of MS-DOS time (nil means UTC)
+	entries   int            // number of files
+	lazy      bool           // central directory is read on demand
+	index     *nameIndex     // name index (nil if not built)
+	fold      bool           // compare names case-insensitively
 
 	Files   []*File
 	Comment string
 }
 
-// NewWriter returns zip.Reader that reads from io.ReadSeeker.
+// ReaderOptions represents options of zip.Reader.
+// Zero value of each limit means no limit.
+type ReaderOptions struct {
+	MaxEntries               int   // maximum number of files
+	MaxTotalUncompressedSize int64 // maximum total uncompressed size of all files
+	MaxCompressionRatio      int64 // maximum ratio of uncompressed size to compressed size per file
+	MaxNameLength            int   // maximum length of a file name
+	MaxExtraLength           int   // maximum length of extra fields per file
+	MaxCommentLength         int   // maximum length of a file comment and the archive comment
+	RejectOverlapping        bool  // reject files whose data overlaps with the other files
+
+	// Location is the time zone of MS-DOS time in the headers.
+	// It is used only if the file has no timestamp extra fields. If nil, UTC is used.
+	Location *time.Location
+
+	// Lazy does not read the central directory in NewReaderWithOptions, and Files is nil.
+	// Files are read on demand with Reader.Iterate and Reader.Lookup.
+	// RejectOverlapping and MaxTotalUncompressedSize are not checked.
+	Lazy bool
+
+	// CaseInsensitive compares names case-insensitively in Lookup, File, Glob and ListDir,
+	// and treats backslashes as slashes (for archives created on Windows).
+	CaseInsensitive bool
+}
+
+// NewReader returns zip.Reader that reads from io.ReadSeeker.
 func NewReader(r io.ReadSeeker) (*Reader, error) {
+	return NewReaderWithOptions(r, nil)
+}
+
+// NewReaderWithOptions returns zip.Reader that reads from io.ReadSeeker with options.
+// Limits are checked for the headers in NewReaderWithOptions,
+// and for the decompressed data while reading from File.Open.
+// If opts is nil, the default options are used.
+func NewReaderWithOptions(r io.ReadSeeker, opts *ReaderOptions) (*Reader, error) {
 	zr := &Reader{
-		r: r,
+		r:      r,
+		starts: []int64{0},
+	}
+	if opts != nil {
+		zr.limit = &readerLimit{opts: *opts}
+		zr.loc = opts.Location
+		zr.lazy = opts.Lazy
+		zr.fold = opts.CaseInsensitive
 	}
 
 	if err := zr.init(); err != nil {
@@ -30,52 +83,126 @@ func NewReader(r io.ReadSeeker) (*Reader, error) {
 
 // init parses all data in zip archive.
 func (r *Reader) init() error {
-	offset, err := findEndCentralDirectory(r.r)
+	offset, tail, err := findEndCentralDirectory(r.r, !r.lazy)
 	if err != nil {
 		return err
 	}
-	if _, err := r.r.Seek(offset, io.SeekStart); err != nil {
-		return err
-	}
 
 	enddir := new(endCentralDirectory)
-	if _, err := enddir.ReadFrom(r.r); err != nil {
-		return err
+	if _, err := enddir.ReadFrom(bytes.NewReader(tail.from(offset))); err != nil {
+		return withPosition(err, "", offset)
 	}
 	r.Comment = string(enddir.comment)
-
-	r.Files = make([]*File, enddir.numberOfEntries)
-	if _, err := r.r.Seek(int64(enddir.offsetCentralDirectory), io.SeekStart); err != nil {
+	if err := r.limit.checkArchive(enddir); err != nil {
 		return err
 	}
-	for i := 0; i < int(enddir.numberOfEntries); i++ {
-		cdir := new(centralDirectoryHeader)
-		if _, err := cdir.ReadFrom(r.r); err != nil {
-			return err
+
+	if len(r.starts) == 1 {
+		if enddir.numberOfDisk != 0 || enddir.numberOfStartDirDisk != 0 || enddir.numberOfEntriesThisDisk != enddir.numberOfEntries {
+			return wrapError(ErrUnsupported, "split zip file: use NewSplitReader")
 		}
 
-		r.Files[i], err = newFile(r.r, cdir)
+		// the central directory ends at the end of central directory record.
+		// the difference is the size of the data prepended to the zip archive.
+		base := offset - int64(enddir.offsetCentralDirectory) - int64(enddir.sizeOfCentralDirectories)
+		if base < 0 {
+			return newError(ErrFormat, "", offset, "central directory is out of range")
+		}
+		r.starts[0] = base
+	}
+	if int(enddir.numberOfDisk) != len(r.starts)-1 {
+		return wrapError(ErrFormat, "number of volumes=%d, want=%d", len(r.starts), int(enddir.numberOfDisk)+1)
+	}
+	if enddir.numberOfStartDirDisk > enddir.numberOfDisk {
+		return newError(ErrFormat, "", offset, "central directory disk number=%d is out of range", enddir.numberOfStartDirDisk)
+	}
+
+	r.entries = int(enddir.numberOfEntries)
+	r.dirOffset = r.starts[enddir.numberOfStartDirDisk] + int64(enddir.offsetCentralDirectory)
+	if r.lazy {
+		return nil
+	}
+
+	// the central directory is read at once, and parsed from memory
+	dir, err := tail.readAt(r.r, r.dirOffset, int64(enddir.sizeOfCentralDirectories))
+	if err != nil {
+		return withPosition(err, "", r.dirOffset)
+	}
+	src := bytes.NewReader(dir)
+	r.Files = make([]*File, r.entries)
+	pos := r.dirOffset
+	for i := 0; i < r.entries; i++ {
+		r.Files[i], pos, err = r.parseFile(src, pos)
 		if err != nil {
 			return err
 		}
 	}
+	if err := r.limit.checkTotal(r.Files); err != nil {
+		return err
+	}
+	if err := r.limit.checkOverlapping(r.Files); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// readFile reads the central directory header at pos,
+// and returns the file and the offset of the next header.
+func (r *Reader) readFile(pos int64) (*File, int64, error) {
+	if _, err := r.r.Seek(pos, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return r.parseFile(r.r, pos)
+}
+
+// parseFile parses the central directory header at pos from src,
+// and returns the file and the offset of the next header.
+func (r *Reader) parseFile(src io.Reader, pos int64) (*File, int64, error) {
+	cdir := new(centralDirectoryHeader)
+	n, err := cdir.ReadFrom(src)
+	if err != nil {
+		return nil, 0, withPosition(err, "", pos)
+	}
+	if int(cdir.diskNumber) >= len(r.starts) {
+		return nil, 0, newError(ErrFormat, string(cdir.fileName), pos, "disk number=%d is out of range", cdir.diskNumber)
+	}
+
+	offset := r.starts[cdir.diskNumber] + int64(cdir.localHeaderOffset)
+	file, err := newFile(r.r, cdir, offset)
+	if err != nil {
+		return nil, 0, withPosition(err, string(cdir.fileName), pos)
+	}
+	file.limit = r.limit
+	if r.loc != nil {
+		file.ModifiedTime = file.modifiedTime(r.loc)
+	}
+	if err := r.limit.checkFile(cdir, file); err != nil {
+		return nil, 0, err
+	}
+	return file, pos + n, nil
+}
+
+// BaseOffset returns the size of the data prepended to the zip archive
+// (e.g. self-extracting stub, shell script) that offsets in the archive do not include.
+func (r *Reader) BaseOffset() int64 {
+	return r.starts[0]
+}
+
 // File represents a single file in zip archive.
 type File struct {
 	FileHeader
 
 	r      io.ReadSeeker
-	offset uint32
+	offset int64        // offset of the local file header in r
+	limit  *readerLimit // resource limits (nil if no limits)
 }
 
 // newFile returns zip.File that reads from io.ReadSeeker.
-func newFile(r io.ReadSeeker, cdir *centralDirectoryHeader) (*File, error) {
+func newFile(r io.ReadSeeker, cdir *centralDirectoryHeader, offset int64) (*File, error) {
 	file := &File{
 		r:      r,
-		offset: cdir.localHeaderOffset,
+		offset: offset,
 	}
 
 	err := cdir.copyToHeader(&file.FileHeader)
@@ -83,44 +210,173 @@ func newFile(r io.ReadSeeker, cdir *centralDirectoryHeader) (*File, error) {
 }
 
 // Open returns io.ReadCloser, which reads from the decompressed contents.
+// The CRC-32 and the size of the decompressed contents are checked at the end of reading.
 func (f *File) Open() (io.ReadCloser, error) {
+	if f.Flags.Encrypted {
+		return nil, &Error{Name: f.FileName, Offset: f.offset, Err: ErrPassword}
+	}
 	r, err := f.OpenRaw()
 	if err != nil {
 		return nil, err
 	}
 
-	return f.Method.newDecompressor(r)
+	dr, err := f.Method.newDecompressor(r)
+	if err != nil {
+		return nil, withPosition(err, f.FileName, f.offset)
+	}
+	dr = &checksumReader{
+		r:    dr,
+		f:    f,
+		hash: crc32.NewIEEE(),
+	}
+	if f.limit != nil {
+		dr = f.limit.newReader(f, dr)
+	}
+	return dr, nil
+}
+
+// LinkTarget returns the target of the symbolic link.
+func (f *File) LinkTarget() (string, error) {
+	if !f.IsSymlink() {
+		return "", &fs.PathError{Op: "readlink", Path: f.FileName, Err: fs.ErrInvalid}
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, io.LimitReader(r, maxLinkTargetSize+1)); err != nil {
+		return "", err
+	}
+	if buf.Len() > maxLinkTargetSize {
+		return "", newError(ErrFormat, f.FileName, f.offset, "symbolic link target is too long")
+	}
+	if buf.Len() == 0 {
+		return "", newError(ErrFormat, f.FileName, f.offset, "symbolic link target is empty")
+	}
+	return buf.String(), nil
+}
+
+// checksumReader implements io.ReadCloser that checks the CRC-32 and the size of the file.
+type checksumReader struct {
+	r    io.ReadCloser
+	f    *File
+	hash hash.Hash32
+	n    int64 // decompressed size
+}
+
+// Read implements the standard Read interface.
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+	if err != io.EOF {
+		return n, err
+	}
+
+	if r.n != int64(r.f.UncompressedSize) {
+		return n, newError(ErrChecksum, r.f.FileName, r.f.offset, "uncompressed size=%d, want=%d", r.n, r.f.UncompressedSize)
+	}
+	if sum := r.hash.Sum32(); sum != r.f.CRC32 {
+		return n, newError(ErrChecksum, r.f.FileName, r.f.offset, "CRC-32=%08x, want=%08x", sum, r.f.CRC32)
+	}
+	return n, io.EOF
 }
 
+// Close implements the standard Close interface.
+func (r *checksumReader) Close() error {
+	return r.r.Close()
+}
+
+// maxLinkTargetSize is the maximum size of a symbolic link target (PATH_MAX).
+const maxLinkTargetSize = 4096
+
 // Open returns io.ReadCloser, which reads from the compressed contents.
 func (f *File) OpenRaw() (io.ReadCloser, error) {
-	if _, err := f.r.Seek(int64(f.offset), io.SeekStart); err != nil {
+	if _, err := f.dataOffset(); err != nil {
 		return nil, err
 	}
 
+	r := io.LimitReader(f.r, int64(f.CompressedSize))
+	return &nopReadCloser{r}, nil
+}
+
+// dataOffset reads the local file header, and returns the offset of the compressed data.
+// The position of the source is left at the compressed data.
+func (f *File) dataOffset() (int64, error) {
+	if _, err := f.r.Seek(f.offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
 	h := new(localFileHeader)
-	if _, err := h.ReadFrom(f.r); err != nil {
-		return nil, err
+	n, err := h.ReadFrom(f.r)
+	if err != nil {
+		return 0, withPosition(err, f.FileName, f.offset)
 	}
 	// simple name check
 	if f.FileName != string(h.fileName) {
-		return nil, fmt.Errorf("broken zip: file name is different %q", f.FileName)
+		return 0, newError(ErrFormat, f.FileName, f.offset, "local file name %q is different", h.fileName)
 	}
+	return f.offset + n, nil
+}
 
-	r := io.LimitReader(f.r, int64(f.CompressedSize))
-	return &nopReadCloser{r}, nil
+// tailData is the data read from the end of the file to find the end of central directory record.
+type tailData struct {
+	offset int64 // offset of buf in the file
+	buf    []byte
+}
+
+// from returns the data from offset to the end of the file.
+func (t *tailData) from(offset int64) []byte {
+	return t.buf[offset-t.offset:]
 }
 
-// findEndCentralDirectory returns the offset of the EndCentralDirectory in io.ReadSeeker.
-func findEndCentralDirectory(r io.ReadSeeker) (offset int64, err error) {
+// readAt returns size bytes at offset. The data in the tail is not read again.
+func (t *tailData) readAt(r io.ReadSeeker, offset, size int64) ([]byte, error) {
+	if offset >= t.offset && offset+size <= t.offset+int64(len(t.buf)) {
+		return t.buf[offset-t.offset : offset-t.offset+size], nil
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// extend reads the data from offset to the start of the tail, and prepends it to the tail.
+func (t *tailData) extend(r io.ReadSeeker, offset int64) error {
+	if offset >= t.offset {
+		return nil
+	}
+	buf, err := t.readAt(r, offset, t.offset-offset)
+	if err != nil {
+		return err
+	}
+	t.buf = append(buf, t.buf...)
+	t.offset = offset
+	return nil
+}
+
+// findEndCentralDirectory returns the offset of the EndCentralDirectory in io.ReadSeeker,
+// and the data read from the end of the file that contains it.
+// It searches backwards for the signature, because the archive comment or stored data
+// may contain the signature, and returns the last candidate that passes validation.
+// If keep is true, the central directory is also read into the tail.
+func findEndCentralDirectory(r io.ReadSeeker, keep bool) (offset int64, tail *tailData, err error) {
 	// get size
 	startOffset, err := r.Seek(0, io.SeekStart)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	endOffset, err := r.Seek(0, io.SeekEnd)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	filesize := endOffset - startOffset
 
@@ -130,18 +386,91 @@ func findEndCentralDirectory(r io.ReadSeeker) (offset int64, err error) {
 		size = filesize
 	}
 	if offset, err = r.Seek(-size, io.SeekEnd); err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	buf := make([]byte, size)
-	if _, err := r.Read(buf); err != nil {
-		return 0, err
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, nil, err
+	}
+	tail = &tailData{offset: offset, buf: buf}
+
+	reasons := make([]string, 0)
+	for index := len(buf); ; {
+		index = bytes.LastIndex(buf[:index], []byte(signEndCentralDirectory))
+		if index < 0 {
+			break
+		}
+
+		candidate := offset + int64(index)
+		err := validateEndCentralDirectory(r, tail, candidate, keep)
+		if err == nil {
+			return candidate, tail, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("offset %d: %v", candidate, err))
+	}
+
+	if len(reasons) == 0 {
+		return 0, nil, wrapError(ErrFormat, "not found end of central directory signature")
+	}
+	return 0, nil, wrapError(ErrFormat, "not found valid end of central directory record (%s)", strings.Join(reasons, "; "))
+}
+
+// validateEndCentralDirectory validates the end of central directory record candidate at offset.
+// If keep is true, the central directory is read into the tail to parse it without reading again.
+// Otherwise only the signature of the first central directory header is read.
+func validateEndCentralDirectory(r io.ReadSeeker, tail *tailData, offset int64, keep bool) error {
+	buf := tail.from(offset)
+	if len(buf) < sizeEndCentralDirectory {
+		return errors.New("record is truncated")
+	}
+
+	enddir := new(endCentralDirectory)
+	if _, err := enddir.ReadFrom(bytes.NewReader(buf)); err != nil {
+		return fmt.Errorf("comment length exceeds the file end: %v", err)
 	}
-	index := bytes.Index(buf, []byte(signEndCentralDirectory))
-	if index < 0 {
-		return 0, errors.New("invalid zip format: not found end of central directory signature")
+	if size := sizeEndCentralDirectory + len(enddir.comment); size != len(buf) {
+		return fmt.Errorf("comment length does not match the file end: record ends at %d, file ends at %d", offset+int64(size), offset+int64(len(buf)))
+	}
+	if enddir.numberOfEntriesThisDisk > enddir.numberOfEntries {
+		return fmt.Errorf("number of entries on this disk=%d exceeds total=%d", enddir.numberOfEntriesThisDisk, enddir.numberOfEntries)
+	}
+	if enddir.numberOfStartDirDisk > enddir.numberOfDisk {
+		return fmt.Errorf("central directory disk number=%d exceeds disk number=%d", enddir.numberOfStartDirDisk, enddir.numberOfDisk)
+	}
+	if enddir.numberOfStartDirDisk != enddir.numberOfDisk {
+		// the central directory starts in the other volume
+		return nil
+	}
+
+	dirSize := int64(enddir.sizeOfCentralDirectories)
+	if int64(enddir.offsetCentralDirectory)+dirSize > offset {
+		return fmt.Errorf("central directory (offset=%d, size=%d) is out of range", enddir.offsetCentralDirectory, enddir.sizeOfCentralDirectories)
+	}
+	if enddir.numberOfEntriesThisDisk == 0 {
+		if dirSize != 0 {
+			return fmt.Errorf("central directory size=%d for no entries", dirSize)
+		}
+		return nil
+	}
+	if dirSize < int64(enddir.numberOfEntriesThisDisk)*int64(sizeCentralDirectoryHeader) {
+		return fmt.Errorf("central directory size=%d is too small for %d entries", dirSize, enddir.numberOfEntriesThisDisk)
 	}
 
-	offset += int64(index)
-	return offset, nil
+	// the central directory is located just before the record,
+	// even if data is prepended to the zip archive.
+	start := offset - dirSize
+	if keep {
+		if err := tail.extend(r, start); err != nil {
+			return err
+		}
+	}
+	sign, err := tail.readAt(r, start, 4)
+	if err != nil {
+		return err
+	}
+	if string(sign) != signCentralDirectoryHeader {
+		return fmt.Errorf("central directory header signature not found at offset %d", offset-dirSize)
+	}
+	return nil
 }
@@ -2,10 +2,15 @@ package zip
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"go-mylib/buffer"
 )
 
 type testcase struct {
@@ -120,3 +125,161 @@ func testcaseCompare(t *testing.T, r io.ReadSeeker, tt testcase) {
 		t.Fatalf("%s: zf.Files[0] content=%q, want=%q", tt.path, content, tt.content)
 	}
 }
+
+func TestReaderPrefix(t *testing.T) {
+	prefix := "#!/bin/sh\nexit 0\n"
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			bin, err := os.ReadFile("tests/" + tt.path)
+			if err != nil {
+				t.Fatalf("os.ReadFile error=%v", err)
+			}
+			src := append([]byte(prefix), bin...)
+
+			zr, err := NewReader(bytes.NewReader(src))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			if zr.BaseOffset() != int64(len(prefix)) {
+				t.Errorf("BaseOffset=%d, want=%d", zr.BaseOffset(), len(prefix))
+			}
+
+			testcaseCompare(t, bytes.NewReader(src), tt)
+		})
+	}
+}
+
+// countReader implements io.ReadSeeker that counts Read calls.
+type countReader struct {
+	io.ReadSeeker
+	reads int
+	n     int64 // read bytes
+}
+
+// Read implements the standard Read interface.
+func (r *countReader) Read(p []byte) (int, error) {
+	r.reads++
+	n, err := r.ReadSeeker.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func TestReaderCentralDirectoryReads(t *testing.T) {
+	tests := []struct {
+		files int
+		lazy  bool
+		reads int // the tail, and the central directory (or its signature in lazy mode) if it is not in the tail
+	}{
+		{10, false, 1},
+		{5000, false, 2},
+		{10, true, 1},
+		{5000, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("files=%d,lazy=%v", tt.files, tt.lazy), func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			for i := 0; i < tt.files; i++ {
+				if _, err := zw.Create(fmt.Sprintf("file%05d.txt", i)); err != nil {
+					t.Fatalf("Writer.Create error=%v", err)
+				}
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			r := &countReader{ReadSeeker: bytes.NewReader(buf.Bytes())}
+			zr, err := NewReaderWithOptions(r, &ReaderOptions{Lazy: tt.lazy})
+			if err != nil {
+				t.Fatalf("NewReaderWithOptions error=%v", err)
+			}
+			if r.reads != tt.reads {
+				t.Fatalf("Read calls=%d, want=%d", r.reads, tt.reads)
+			}
+			// the central directory is not read in lazy mode
+			if max := int64(math.MaxUint16 + sizeEndCentralDirectory + 4); tt.lazy && r.n > max {
+				t.Fatalf("read size=%d, want<=%d", r.n, max)
+			}
+			if tt.lazy {
+				return
+			}
+			if len(zr.Files) != tt.files {
+				t.Fatalf("Files size=%d, want=%d", len(zr.Files), tt.files)
+			}
+		})
+	}
+}
+
+func TestReaderEndCentralDirectory(t *testing.T) {
+	fake := new(bytes.Buffer)
+	(&endCentralDirectory{
+		numberOfEntriesThisDisk:  1,
+		numberOfEntries:          1,
+		sizeOfCentralDirectories: 0x100,
+		offsetCentralDirectory:   0x10,
+	}).WriteTo(fake)
+
+	tests := []struct {
+		name    string
+		content string
+		comment string
+	}{
+		{"signature-in-comment", "content", "comment" + fake.String()},
+		{"signature-in-content", "content" + fake.String(), "comment"},
+		{"signature-in-both", fake.String(), fake.String() + "comment" + fake.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			zw.Comment = tt.comment
+
+			fh := NewFileHeader("test.txt")
+			fh.Method = &MethodStore{}
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if _, err := fw.Write([]byte(tt.content)); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReader(buffer.NewReader(buf))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			if zr.Comment != tt.comment {
+				t.Errorf("zip comment=%q, want=%q", zr.Comment, tt.comment)
+			}
+			if len(zr.Files) != 1 {
+				t.Fatalf("Reader.Files size=%d, want=%d", len(zr.Files), 1)
+			}
+			if got := readAll(t, zr.Files[0]); got != tt.content {
+				t.Errorf("content=%q, want=%q", got, tt.content)
+			}
+
+			// truncated archive
+			bin := buf.Bytes()
+			_, err = NewReader(bytes.NewReader(bin[:len(bin)-1]))
+			if err == nil {
+				t.Fatalf("NewReader error=nil, want error")
+			}
+			if !strings.Contains(err.Error(), "comment length") {
+				t.Errorf("NewReader error=%q, want comment length error", err)
+			}
+		})
+	}
+}
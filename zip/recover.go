@@ -0,0 +1,249 @@
+package zip
+
+import (
+	"bufio"
+	"bytes"
+	"hash/crc32"
+	"io"
+)
+
+// NewRecoveryReader returns zip.Reader that contains the files recovered from io.ReadSeeker.
+// It does not use the central directory, and scans the data for local file headers instead.
+// Files whose data is broken (e.g. truncated, CRC-32 mismatch) are skipped.
+// The recovered files have only the information in the local file header and the data descriptor.
+func NewRecoveryReader(r io.ReadSeeker) (*Reader, error) {
+	zr := &Reader{
+		r:      r,
+		starts: []int64{0},
+		Files:  make([]*File, 0),
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	zr.dirOffset = size
+
+	offset := int64(0)
+	for offset < size {
+		offset, err = findSignature(r, offset, signLocalFileHeader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		file, end, err := recoverFile(r, offset, size)
+		if err != nil {
+			// not a valid entry, search the next signature
+			offset += 1
+			continue
+		}
+		zr.Files = append(zr.Files, file)
+		offset = end
+	}
+
+	return zr, nil
+}
+
+// Repair writes a new zip archive from the files recovered from io.ReadSeeker.
+// It returns the number of recovered files.
+// Entries with the same name are all kept, since a damaged archive may have
+// several copies of an entry, and it is not clear which one is correct.
+func Repair(w io.WriteSeeker, r io.ReadSeeker) (int, error) {
+	zr, err := NewRecoveryReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	zw, err := NewWriterWithOptions(w, &WriterOptions{AllowDuplicates: true})
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range zr.Files {
+		if err := zw.Copy(f); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return len(zr.Files), nil
+}
+
+// recoverFile decodes the entry at offset, and returns the file and the end offset of the entry.
+func recoverFile(r io.ReadSeeker, offset int64, size int64) (*File, int64, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	h := new(localFileHeader)
+	n, err := h.ReadFrom(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file := &File{
+		r:      r,
+		offset: offset,
+	}
+	if err := h.copyToHeader(&file.FileHeader); err != nil {
+		return nil, 0, err
+	}
+	start := offset + n
+
+	if !file.Flags.DataDescriptor {
+		end := start + int64(file.CompressedSize)
+		if end > size {
+			return nil, 0, wrapError(ErrFormat, "file data is truncated")
+		}
+		if err := checkFileData(r, file, start); err != nil {
+			return nil, 0, err
+		}
+		return file, end, nil
+	}
+
+	// the sizes are stored in the data descriptor
+	var compressedSize int64
+	switch file.Method.(type) {
+	case *MethodStore:
+		compressedSize, err = findStoredDataSize(r, start)
+	default:
+		compressedSize, err = findCompressedDataSize(r, file.Method, start)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := r.Seek(start+compressedSize, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	dd := new(dataDescriptor)
+	ddsize, err := dd.ReadFrom(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(dd.compressedSize) != compressedSize {
+		return nil, 0, wrapError(ErrFormat, "compressed size does not match the data descriptor")
+	}
+
+	file.CRC32 = dd.crc32
+	file.CompressedSize = dd.compressedSize
+	file.UncompressedSize = dd.uncompressedSize
+	if err := checkFileData(r, file, start); err != nil {
+		return nil, 0, err
+	}
+	return file, start + compressedSize + ddsize, nil
+}
+
+// checkFileData decompresses the file data at start, and validates CRC-32 and size.
+func checkFileData(r io.ReadSeeker, file *File, start int64) error {
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	dr, err := file.Method.newDecompressor(io.LimitReader(r, int64(file.CompressedSize)))
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+
+	hash := crc32.NewIEEE()
+	n, err := io.Copy(hash, dr)
+	if err != nil {
+		return err
+	}
+	if n != int64(file.UncompressedSize) {
+		return wrapError(ErrChecksum, "uncompressed size=%d, want=%d", n, file.UncompressedSize)
+	}
+	if hash.Sum32() != file.CRC32 {
+		return wrapError(ErrChecksum, "CRC-32=%08x, want=%08x", hash.Sum32(), file.CRC32)
+	}
+	return nil
+}
+
+// findStoredDataSize returns the size of the stored data at start.
+// The data must be followed by the data descriptor with the signature.
+func findStoredDataSize(r io.ReadSeeker, start int64) (int64, error) {
+	offset := start
+	for {
+		var err error
+		offset, err = findSignature(r, offset, signDataDescriptor)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		dd := new(dataDescriptor)
+		if _, err := dd.ReadFrom(r); err == nil && int64(dd.compressedSize) == offset-start {
+			return offset - start, nil
+		}
+		offset += 1
+	}
+}
+
+// findCompressedDataSize returns the size of the compressed data at start.
+// The compressed data must be self-terminating.
+func findCompressedDataSize(r io.ReadSeeker, method MethodType, start int64) (int64, error) {
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	cr := &byteCountReader{r: bufio.NewReader(r)}
+	dr, err := method.newDecompressor(cr)
+	if err != nil {
+		return 0, err
+	}
+	defer dr.Close()
+
+	if _, err := io.Copy(io.Discard, dr); err != nil {
+		return 0, err
+	}
+	return cr.count, nil
+}
+
+// findSignature returns the offset of the signature after offset.
+// It returns io.EOF if the signature is not found.
+func findSignature(r io.ReadSeeker, offset int64, sign string) (int64, error) {
+	buf := make([]byte, 32*1024)
+	for {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		n, err := io.ReadFull(r, buf)
+		if index := bytes.Index(buf[:n], []byte(sign)); index >= 0 {
+			return offset + int64(index), nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(n - len(sign) + 1)
+	}
+}
+
+// byteCountReader implements io.ByteReader and counts the size of the read data.
+// Decompressors do not read ahead from io.ByteReader.
+type byteCountReader struct {
+	r     *bufio.Reader
+	count int64
+}
+
+// Read implements the standard Read interface.
+func (r *byteCountReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// ReadByte implements the standard ReadByte interface.
+func (r *byteCountReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.count += 1
+	}
+	return b, err
+}
@@ -0,0 +1,138 @@
+package zip
+
+import (
+	"bytes"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestRecoveryReader(t *testing.T) {
+	files := []struct {
+		name    string
+		content string
+		method  MethodType
+		flags   FlagType
+	}{
+		{"deflate-dd.txt", "deflate content with data descriptor", &MethodDeflated{}, FlagType{DataDescriptor: true}},
+		{"deflate.txt", "deflate content", &MethodDeflated{}, FlagType{}},
+		{"store-dd.txt", "store content with data descriptor", &MethodStore{}, FlagType{DataDescriptor: true}},
+		{"empty-dd.txt", "", &MethodDeflated{}, FlagType{DataDescriptor: true}},
+		{"store.txt", "store content", &MethodStore{}, FlagType{}},
+	}
+
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	ends := make([]int, 0)
+	for _, file := range files {
+		fh := NewFileHeader(file.name)
+		fh.Method = file.method
+		fh.Flags = file.flags
+		fw, err := zw.CreateFromHeader(fh)
+		if err != nil {
+			t.Fatalf("Writer.CreateFromHeader error=%v", err)
+		}
+		if _, err := fw.Write([]byte(file.content)); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+		if err := fw.Close(); err != nil {
+			t.Fatalf("FileWriter.Close error=%v", err)
+		}
+		if err := zw.Flush(); err != nil {
+			t.Fatalf("Writer.Flush error=%v", err)
+		}
+		ends = append(ends, buf.Len())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		recover []int // indexes of recovered files
+	}{
+		{
+			name:    "complete",
+			data:    src,
+			recover: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:    "no-central-directory",
+			data:    src[:ends[len(ends)-1]],
+			recover: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:    "truncated",
+			data:    src[:ends[2]+40],
+			recover: []int{0, 1, 2},
+		},
+		{
+			name: "broken-data",
+			data: func() []byte {
+				data := append([]byte{}, src[:ends[3]]...)
+				data[ends[1]-1] ^= 0xff // last byte of deflate.txt
+				return data
+			}(),
+			recover: []int{0, 2, 3},
+		},
+		{
+			name: "duplicated",
+			data: func() []byte {
+				data := append([]byte{}, src[:ends[1]]...)
+				return append(data, src[ends[0]:ends[1]]...) // deflate.txt twice
+			}(),
+			recover: []int{0, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zr, err := NewRecoveryReader(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("NewRecoveryReader error=%v", err)
+			}
+			testRecoveredFiles(t, zr, tt.recover, func(i int) (string, string) {
+				return files[i].name, files[i].content
+			})
+
+			// repair
+			out := new(buffer.Buffer)
+			n, err := Repair(buffer.NewWriter(out), bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Repair error=%v", err)
+			}
+			if n != len(tt.recover) {
+				t.Errorf("Repair recovered=%d, want=%d", n, len(tt.recover))
+			}
+			zr, err = NewReader(buffer.NewReader(out))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			testRecoveredFiles(t, zr, tt.recover, func(i int) (string, string) {
+				return files[i].name, files[i].content
+			})
+		})
+	}
+}
+
+func testRecoveredFiles(t *testing.T, zr *Reader, indexes []int, expect func(int) (string, string)) {
+	t.Helper()
+
+	if len(zr.Files) != len(indexes) {
+		t.Fatalf("Reader.Files size=%d, want=%d", len(zr.Files), len(indexes))
+	}
+	for i, f := range zr.Files {
+		name, content := expect(indexes[i])
+		if f.FileName != name {
+			t.Errorf("Files[%d] name=%q, want=%q", i, f.FileName, name)
+		}
+		if got := readAll(t, f); got != content {
+			t.Errorf("%s: content=%q, want=%q", f.FileName, got, content)
+		}
+	}
+}
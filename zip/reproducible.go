@@ -0,0 +1,130 @@
+package zip
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-mylib/buffer"
+)
+
+// reproducible holds the state of zip.Writer in reproducible mode.
+// Entries are kept in memory until Close, and written in name order.
+type reproducible struct {
+	epoch   time.Time       // modified time of all files
+	entries []*pendingEntry // entries not yet written
+	pending *pendingEntry   // entry being written (nil if none)
+}
+
+// pendingEntry represents an entry kept in memory.
+type pendingEntry struct {
+	h   *centralDirectoryHeader
+	buf *buffer.Buffer // local file header, data and data descriptor
+}
+
+// newReproducible returns reproducible with the modified time of all files.
+// If epoch is zero, SOURCE_DATE_EPOCH environment variable is used,
+// or 1980-01-01 00:00:00 UTC (the minimum of MS-DOS time) if it is not set.
+// The epoch is clamped to the MS-DOS time range and rounded down to even seconds in UTC,
+// so that it is stored exactly in the MS-DOS time without timestamp extra fields.
+func newReproducible(epoch time.Time) (*reproducible, error) {
+	if epoch.IsZero() {
+		epoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+		if s := os.Getenv("SOURCE_DATE_EPOCH"); s != "" {
+			sec, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, wrapError(fs.ErrInvalid, "SOURCE_DATE_EPOCH=%q", s)
+			}
+			epoch = time.Unix(sec, 0).UTC()
+		}
+	}
+
+	epoch = epoch.UTC()
+	switch {
+	case epoch.Year() < dosMinYear:
+		epoch = time.Date(dosMinYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	case epoch.Year() > dosMaxYear:
+		epoch = time.Date(dosMaxYear, 12, 31, 23, 59, 58, 0, time.UTC)
+	}
+	epoch = epoch.Truncate(2 * time.Second)
+
+	return &reproducible{
+		epoch:   epoch,
+		entries: make([]*pendingEntry, 0),
+	}, nil
+}
+
+// normalize replaces the fields that depend on the environment with fixed values.
+// The file name, comment, compression method and data are not changed.
+func (r *reproducible) normalize(fh *FileHeader) {
+	mode := fh.Mode()
+	switch {
+	case mode.IsDir():
+		mode = fs.ModeDir | 0755
+	case mode&fs.ModeSymlink != 0:
+		mode = fs.ModeSymlink | 0777
+	case mode&0111 != 0:
+		mode = 0755
+	default:
+		mode = 0644
+	}
+
+	fh.MinimumVersion = fh.minimumVersion()
+	fh.GenerateVersion = versionGenerated
+	fh.GenerateOS = OS_UNIX
+	fh.ModifiedTime = r.epoch
+	fh.ExternalFileAttr = 0
+	fh.SetMode(mode)
+
+	extras := make([]ExtraField, 0, len(fh.ExtraFields))
+	for _, extra := range fh.ExtraFields {
+		switch extra.(type) {
+		case *ExtraNTFS, *ExtraExtendedTimestamp:
+			// timestamps
+		default:
+			extras = append(extras, extra)
+		}
+	}
+	fh.ExtraFields = extras
+}
+
+// writer returns the writer for the entry. The entry is kept after add is called.
+func (r *reproducible) writer(h *centralDirectoryHeader) io.WriteSeeker {
+	r.pending = &pendingEntry{
+		h:   h,
+		buf: new(buffer.Buffer),
+	}
+	return buffer.NewWriter(r.pending.buf)
+}
+
+// add adds the entry written by the writer.
+func (r *reproducible) add(h *centralDirectoryHeader) {
+	if r.pending == nil || r.pending.h != h {
+		return
+	}
+	r.entries = append(r.entries, r.pending)
+	r.pending = nil
+}
+
+// flush writes the entries in name order.
+func (r *reproducible) flush(w *Writer) error {
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		return string(r.entries[i].h.fileName) < string(r.entries[j].h.fileName)
+	})
+
+	for _, e := range r.entries {
+		if err := w.locate(e.h); err != nil {
+			return err
+		}
+
+		if _, err := w.w.Write(e.buf.Bytes()); err != nil {
+			return err
+		}
+		w.dirs = append(w.dirs, e.h)
+	}
+	r.entries = r.entries[:0]
+	return nil
+}
@@ -0,0 +1,175 @@
+package zip
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"go-mylib/buffer"
+)
+
+func TestWriterReproducible(t *testing.T) {
+	type file struct {
+		name    string
+		content string
+		mode    fs.FileMode
+		os      OSType
+		mtime   time.Time
+	}
+	write := func(files []file, opts *WriterOptions) []byte {
+		buf := new(buffer.Buffer)
+		zw, err := NewWriterWithOptions(buffer.NewWriter(buf), opts)
+		if err != nil {
+			t.Fatalf("NewWriterWithOptions error=%v", err)
+		}
+		for _, f := range files {
+			fh := NewFileHeader(f.name)
+			fh.GenerateOS = f.os
+			fh.ModifiedTime = f.mtime
+			fh.SetMode(f.mode)
+			fh.ExtraFields = append(fh.ExtraFields, &ExtraNTFS{Mtime: f.mtime, Atime: f.mtime, Ctime: f.mtime})
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if _, err := fw.Write([]byte(f.content)); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Writer.Close error=%v", err)
+		}
+		return buf.Bytes()
+	}
+
+	now := time.Date(2022, 5, 6, 7, 8, 10, 0, time.UTC)
+	later := now.Add(time.Hour)
+	files1 := []file{
+		{"dir/", "", fs.ModeDir | 0700, OS_UNIX, now},
+		{"dir/b.txt", "content of b", 0600, OS_UNIX, now},
+		{"a.sh", "#!/bin/sh", 0750, OS_UNIX, now},
+	}
+	files2 := []file{
+		{"a.sh", "#!/bin/sh", 0711, OS_UNIX, later},
+		{"dir/b.txt", "content of b", 0444, OS_MSDOS, later},
+		{"dir/", "", fs.ModeDir | 0777, OS_MSDOS, later},
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	opts := &WriterOptions{Reproducible: true}
+	zip1 := write(files1, opts)
+	zip2 := write(files2, opts)
+	if !bytes.Equal(zip1, zip2) {
+		t.Fatalf("reproducible archives are different")
+	}
+	if bytes.Equal(write(files1, nil), write(files2, nil)) {
+		t.Fatalf("non-reproducible archives are same")
+	}
+
+	zr, err := NewReader(bytes.NewReader(zip1))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	epoch := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	wants := []struct {
+		name string
+		mode fs.FileMode
+	}{
+		{"a.sh", 0755},
+		{"dir/", fs.ModeDir | 0755},
+		{"dir/b.txt", 0644},
+	}
+	if len(zr.Files) != len(wants) {
+		t.Fatalf("Files size=%d, want=%d", len(zr.Files), len(wants))
+	}
+	for i, want := range wants {
+		f := zr.Files[i]
+		if f.FileName != want.name {
+			t.Fatalf("Files[%d] name=%q, want=%q", i, f.FileName, want.name)
+		}
+		if f.Mode() != want.mode {
+			t.Fatalf("%q mode=%v, want=%v", f.FileName, f.Mode(), want.mode)
+		}
+		if !f.ModifiedTime.Equal(epoch) {
+			t.Fatalf("%q mtime=%v, want=%v", f.FileName, f.ModifiedTime, epoch)
+		}
+		if len(f.ExtraFields) != 0 {
+			t.Fatalf("%q extra fields=%d, want=0", f.FileName, len(f.ExtraFields))
+		}
+		if strings.HasSuffix(f.FileName, ".txt") {
+			if got := string(readAll(t, f)); got != "content of b" {
+				t.Fatalf("%q content=%q", f.FileName, got)
+			}
+		}
+	}
+
+	// SOURCE_DATE_EPOCH
+	t.Setenv("SOURCE_DATE_EPOCH", "1651820890") // 2022-05-06T07:08:10Z
+	zr, err = NewReader(bytes.NewReader(write(files1, opts)))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	if got := zr.Files[0].ModifiedTime; !got.Equal(now) {
+		t.Fatalf("mtime=%v, want=%v", got, now)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "invalid")
+	if _, err := NewWriterWithOptions(buffer.NewWriter(new(buffer.Buffer)), opts); err == nil {
+		t.Fatalf("NewWriterWithOptions error=nil, want error")
+	}
+}
+
+func TestWriterReproducibleEpoch(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name  string
+		env   string
+		opts  WriterOptions
+		epoch time.Time
+	}{
+		{"odd-second", "1700000001", WriterOptions{}, time.Unix(1700000000, 0).UTC()},
+		{"before-1980", "0", WriterOptions{}, time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"after-2107", "", WriterOptions{Epoch: time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2107, 12, 31, 23, 59, 58, 0, time.UTC)},
+		{"location", "", WriterOptions{Epoch: time.Date(2022, 5, 6, 16, 8, 11, 500, jst), Location: jst}, time.Date(2022, 5, 6, 7, 8, 10, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.env)
+			opts := tt.opts
+			opts.Reproducible = true
+			buf := new(buffer.Buffer)
+			zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &opts)
+			if err != nil {
+				t.Fatalf("NewWriterWithOptions error=%v", err)
+			}
+			fh := NewFileHeader("a.txt")
+			fh.ModifiedTime = time.Now()
+			if err := zw.CopyFromReader(fh, strings.NewReader("")); err != nil {
+				t.Fatalf("Writer.CopyFromReader error=%v", err)
+			}
+			if _, err := zw.Create("b.txt"); err != nil {
+				t.Fatalf("Writer.Create error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			// the epoch is stored in the MS-DOS time without timestamp extra fields
+			zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			for _, f := range zr.Files {
+				if len(f.ExtraFields) != 0 {
+					t.Fatalf("%q extra fields=%d, want=0", f.FileName, len(f.ExtraFields))
+				}
+				if !f.ModifiedTime.Equal(tt.epoch) {
+					t.Fatalf("%q mtime=%v, want=%v", f.FileName, f.ModifiedTime, tt.epoch)
+				}
+			}
+		})
+	}
+}
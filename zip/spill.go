@@ -0,0 +1,83 @@
+package zip
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// dirSpill stores the central directory headers in io.ReadWriteSeeker instead of memory.
+type dirSpill struct {
+	rw    io.ReadWriteSeeker
+	bw    *bufio.Writer
+	start int64    // start offset in rw
+	count int      // number of stored headers
+	file  *os.File // temporary file (nil if rw is supplied by the caller)
+}
+
+// newDirSpill returns dirSpill that stores the headers in rw.
+// If rw is nil, a temporary file is created.
+func newDirSpill(rw io.ReadWriteSeeker) (*dirSpill, error) {
+	s := &dirSpill{rw: rw}
+	if rw == nil {
+		file, err := os.CreateTemp("", "zip-cdir-*")
+		if err != nil {
+			return nil, err
+		}
+		s.rw, s.file = file, file
+	}
+
+	start, err := s.rw.Seek(0, io.SeekCurrent)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.start = start
+	s.bw = bufio.NewWriter(s.rw)
+	return s, nil
+}
+
+// add stores the completed headers.
+func (s *dirSpill) add(dirs []*centralDirectoryHeader) error {
+	for _, dir := range dirs {
+		if _, err := dir.WriteTo(s.bw); err != nil {
+			return err
+		}
+		s.count++
+	}
+	return nil
+}
+
+// each calls fn for each stored header in the order of add.
+func (s *dirSpill) each(fn func(dir *centralDirectoryHeader) error) error {
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.rw.Seek(s.start, io.SeekStart); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(s.rw)
+	for i := 0; i < s.count; i++ {
+		dir := new(centralDirectoryHeader)
+		if _, err := dir.ReadFrom(br); err != nil {
+			return err
+		}
+		if err := fn(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close removes the temporary file.
+func (s *dirSpill) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
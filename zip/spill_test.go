@@ -0,0 +1,81 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestWriterSpill(t *testing.T) {
+	write := func(opts *WriterOptions, check func(zw *Writer)) []byte {
+		buf := new(buffer.Buffer)
+		zw, err := NewWriterWithOptions(buffer.NewWriter(buf), opts)
+		if err != nil {
+			t.Fatalf("NewWriterWithOptions error=%v", err)
+		}
+		for i := 0; i < 1000; i++ {
+			fh := NewFileHeader(fmt.Sprintf("file%04d.txt", i))
+			fh.Comment = "comment"
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if _, err := fmt.Fprintf(fw, "content %d", i); err != nil {
+				t.Fatalf("FileWriter.Write error=%v", err)
+			}
+			if len(zw.dirs) > 1 && opts != nil {
+				t.Fatalf("headers in memory=%d, want<=1", len(zw.dirs))
+			}
+		}
+		check(zw)
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Writer.Close error=%v", err)
+		}
+		return buf.Bytes()
+	}
+
+	want := write(nil, func(zw *Writer) {})
+
+	// temporary file
+	var tmpname string
+	got := write(&WriterOptions{Spill: true}, func(zw *Writer) {
+		tmpname = zw.spill.file.Name()
+	})
+	if !bytes.Equal(got, want) {
+		t.Fatalf("spilled archive is different")
+	}
+	if _, err := os.Stat(tmpname); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("temporary file is not removed: %v", err)
+	}
+
+	// caller-supplied file
+	file, err := os.Create(filepath.Join(t.TempDir(), "spill"))
+	if err != nil {
+		t.Fatalf("os.Create error=%v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString("header"); err != nil {
+		t.Fatalf("File.WriteString error=%v", err)
+	}
+	got = write(&WriterOptions{Spill: true, SpillFile: file}, func(zw *Writer) {})
+	if !bytes.Equal(got, want) {
+		t.Fatalf("spilled archive is different")
+	}
+
+	zr, err := NewReader(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("NewReader error=%v", err)
+	}
+	if len(zr.Files) != 1000 {
+		t.Fatalf("Files size=%d, want=1000", len(zr.Files))
+	}
+	if got := readAll(t, zr.Files[999]); got != "content 999" {
+		t.Fatalf("content=%q, want=%q", got, "content 999")
+	}
+}
@@ -0,0 +1,264 @@
+package zip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// VolumeOpener returns the volume of a split zip file.
+// disk is the zero-based volume index (.z01 is 0, .z02 is 1, ...).
+type VolumeOpener func(disk int) (io.ReadSeeker, error)
+
+// VolumeCreator returns the writer for a volume of a split zip file.
+// disk is the zero-based volume index (.z01 is 0, .z02 is 1, ...).
+// If the writer implements io.Closer, it is closed when the volume is completed.
+type VolumeCreator func(disk int) (io.Writer, error)
+
+// NewSplitWriter returns zip.Writer that writes a split zip file.
+// Each volume is at most size bytes, and create is called when a new volume is needed.
+// The last volume contains the end of central directory record.
+// All files are written with the data descriptor, because volumes can not be rewritten.
+func NewSplitWriter(size int64, create VolumeCreator) (*Writer, error) {
+	if size < int64(sizeEndCentralDirectory) {
+		return nil, wrapError(fs.ErrInvalid, "volume size is too small: %d", size)
+	}
+
+	vw := &volumeWriter{
+		create: create,
+		size:   size,
+	}
+	// spanning signature
+	if _, err := vw.Write([]byte(signDataDescriptor)); err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		w:     vw,
+		vw:    vw,
+		dirs:  make([]*centralDirectoryHeader, 0),
+		names: newNameSet(),
+	}, nil
+}
+
+// NewSplitReader returns zip.Reader that reads a split zip file.
+// volumes must be ordered (.z01, .z02, ..., .zip).
+func NewSplitReader(volumes []io.ReadSeeker) (*Reader, error) {
+	if len(volumes) == 0 {
+		return nil, wrapError(fs.ErrInvalid, "split zip file has no volume")
+	}
+
+	vr, err := newVolumeReader(volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	zr := &Reader{
+		r:      vr,
+		starts: vr.starts,
+	}
+	if err := zr.init(); err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+// NewSplitReaderFunc returns zip.Reader that reads a split zip file.
+// last is the last volume (.zip), and open is called for the other volumes.
+func NewSplitReaderFunc(last io.ReadSeeker, open VolumeOpener) (*Reader, error) {
+	offset, tail, err := findEndCentralDirectory(last, false)
+	if err != nil {
+		return nil, err
+	}
+
+	enddir := new(endCentralDirectory)
+	if _, err := enddir.ReadFrom(bytes.NewReader(tail.from(offset))); err != nil {
+		return nil, err
+	}
+
+	volumes := make([]io.ReadSeeker, 0, int(enddir.numberOfDisk)+1)
+	for disk := 0; disk < int(enddir.numberOfDisk); disk++ {
+		v, err := open(disk)
+		if err != nil {
+			return nil, fmt.Errorf("volume %d: %w", disk, err)
+		}
+		volumes = append(volumes, v)
+	}
+	volumes = append(volumes, last)
+
+	return NewSplitReader(volumes)
+}
+
+// volumeReader implements io.ReadSeeker that reads volumes as one stream.
+type volumeReader struct {
+	volumes []io.ReadSeeker
+	starts  []int64 // start offset of each volume
+	size    int64   // total size
+	pos     int64   // current position
+}
+
+// newVolumeReader returns volumeReader that reads from volumes.
+func newVolumeReader(volumes []io.ReadSeeker) (*volumeReader, error) {
+	vr := &volumeReader{
+		volumes: volumes,
+		starts:  make([]int64, len(volumes)),
+	}
+
+	for i, v := range volumes {
+		start, err := v.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		vr.starts[i] = vr.size
+		vr.size += end - start
+	}
+	return vr, nil
+}
+
+// Read implements the standard Read interface.
+// Read does not cross a volume boundary.
+func (r *volumeReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	disk := r.disk(r.pos)
+	end := r.size
+	if disk+1 < len(r.starts) {
+		end = r.starts[disk+1]
+	}
+	if remain := end - r.pos; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	v := r.volumes[disk]
+	if _, err := v.Seek(r.pos-r.starts[disk], io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := v.Read(p)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements the standard Seek interface.
+func (r *volumeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		offset += 0
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.pos = offset
+	return r.pos, nil
+}
+
+// disk returns the volume index that contains the offset.
+func (r *volumeReader) disk(offset int64) int {
+	for i := len(r.starts) - 1; i > 0; i-- {
+		if r.starts[i] <= offset {
+			return i
+		}
+	}
+	return 0
+}
+
+// volumeWriter implements io.WriteSeeker that writes a stream to volumes.
+// Seek only supports getting the current position.
+type volumeWriter struct {
+	create VolumeCreator
+	size   int64     // maximum size of a volume
+	w      io.Writer // current volume
+	disk   int       // current volume index
+	offset int64     // offset in the current volume
+	total  int64     // total written size
+}
+
+// Write implements the standard Write interface.
+func (w *volumeWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.w == nil {
+			v, err := w.create(w.disk)
+			if err != nil {
+				return written, fmt.Errorf("volume %d: %w", w.disk, err)
+			}
+			w.w = v
+		}
+		if w.offset >= w.size {
+			if err := w.next(); err != nil {
+				return written, err
+			}
+			continue
+		}
+
+		b := p
+		if remain := w.size - w.offset; int64(len(b)) > remain {
+			b = b[:remain]
+		}
+		n, err := w.w.Write(b)
+		written += n
+		w.offset += int64(n)
+		w.total += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Seek returns the current position. Other seeking is not supported.
+func (w *volumeWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekCurrent {
+		return w.total, nil
+	}
+	return 0, wrapError(ErrUnsupported, "split zip file writer does not support seeking")
+}
+
+// Close closes the current volume.
+func (w *volumeWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// reserve ensures that the next n bytes are written to the same volume.
+func (w *volumeWriter) reserve(n int64) error {
+	if n > w.size {
+		return wrapError(ErrUnsupported, "volume size is too small: %d, need %d", w.size, n)
+	}
+	if w.offset+n > w.size {
+		return w.next()
+	}
+	return nil
+}
+
+// next completes the current volume and starts the next volume.
+func (w *volumeWriter) next() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+	w.w = nil
+	w.disk++
+	w.offset = 0
+	return nil
+}
@@ -0,0 +1,220 @@
+package zip
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"testing"
+	"time"
+)
+
+// splitTestFile represents a file in the split zip file for testing.
+type splitTestFile struct {
+	name    string
+	content string
+}
+
+// buildSplitZip builds a split zip file with stored files.
+// The data area is cut at the cuts offsets.
+// The central directory is written in the last volume.
+func buildSplitZip(t *testing.T, files []splitTestFile, cuts []int64) [][]byte {
+	t.Helper()
+
+	data := new(bytes.Buffer)
+	data.WriteString(signDataDescriptor) // spanning signature
+
+	headers := make([]*centralDirectoryHeader, 0)
+	offsets := make([]int64, 0)
+	for _, file := range files {
+		fh := NewFileHeader(file.name)
+		fh.Method = &MethodStore{}
+		fh.ModifiedTime = time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC)
+		fh.CRC32 = crc32.ChecksumIEEE([]byte(file.content))
+		fh.CompressedSize = uint32(len(file.content))
+		fh.UncompressedSize = uint32(len(file.content))
+
+		lh := new(localFileHeader)
+		if err := lh.copyFromHeader(fh); err != nil {
+			t.Fatalf("copyFromHeader error=%v", err)
+		}
+		ch := new(centralDirectoryHeader)
+		if err := ch.copyFromHeader(fh); err != nil {
+			t.Fatalf("copyFromHeader error=%v", err)
+		}
+
+		offsets = append(offsets, int64(data.Len()))
+		headers = append(headers, ch)
+		lh.WriteTo(data)
+		data.WriteString(file.content)
+	}
+
+	// split data area
+	volumes := make([][]byte, 0)
+	src := data.Bytes()
+	starts := []int64{0}
+	prev := int64(0)
+	for _, cut := range cuts {
+		volumes = append(volumes, src[prev:cut])
+		starts = append(starts, cut)
+		prev = cut
+	}
+	last := bytes.NewBuffer(append([]byte{}, src[prev:]...))
+
+	// central directory
+	cdirOffset := last.Len()
+	for i, h := range headers {
+		disk := 0
+		for j := range starts {
+			if starts[j] <= offsets[i] {
+				disk = j
+			}
+		}
+		h.diskNumber = uint16(disk)
+		h.localHeaderOffset = uint32(offsets[i] - starts[disk])
+		h.WriteTo(last)
+	}
+
+	end := &endCentralDirectory{
+		numberOfDisk:             uint16(len(cuts)),
+		numberOfStartDirDisk:     uint16(len(cuts)),
+		numberOfEntriesThisDisk:  uint16(len(files)),
+		numberOfEntries:          uint16(len(files)),
+		sizeOfCentralDirectories: uint32(last.Len() - cdirOffset),
+		offsetCentralDirectory:   uint32(cdirOffset),
+	}
+	end.WriteTo(last)
+
+	return append(volumes, last.Bytes())
+}
+
+func TestSplitReader(t *testing.T) {
+	files := []splitTestFile{
+		{"file1.txt", "file1 content"},
+		{"file2.txt", "file2 content, which is longer than file1"},
+		{"file3.txt", "file3"},
+	}
+
+	tests := []struct {
+		name string
+		cuts []int64
+	}{
+		{"single-volume", nil},
+		{"header-boundary", []int64{10, 50}},
+		{"data-boundary", []int64{45, 100, 140}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volumes := buildSplitZip(t, files, tt.cuts)
+
+			readers := make([]io.ReadSeeker, len(volumes))
+			for i, v := range volumes {
+				readers[i] = bytes.NewReader(v)
+			}
+			zr, err := NewSplitReader(readers)
+			if err != nil {
+				t.Fatalf("NewSplitReader error=%v", err)
+			}
+			testSplitFiles(t, zr, files)
+
+			open := func(disk int) (io.ReadSeeker, error) {
+				if disk >= len(volumes)-1 {
+					return nil, fmt.Errorf("unexpected disk %d", disk)
+				}
+				return bytes.NewReader(volumes[disk]), nil
+			}
+			zr, err = NewSplitReaderFunc(bytes.NewReader(volumes[len(volumes)-1]), open)
+			if err != nil {
+				t.Fatalf("NewSplitReaderFunc error=%v", err)
+			}
+			testSplitFiles(t, zr, files)
+
+			if len(volumes) > 1 {
+				if _, err := NewReader(bytes.NewReader(volumes[len(volumes)-1])); err == nil {
+					t.Errorf("NewReader error=nil, want error")
+				}
+			}
+		})
+	}
+}
+
+func testSplitFiles(t *testing.T, zr *Reader, files []splitTestFile) {
+	t.Helper()
+
+	if len(zr.Files) != len(files) {
+		t.Fatalf("Reader.Files size=%d, want=%d", len(zr.Files), len(files))
+	}
+	for i, f := range zr.Files {
+		if f.FileName != files[i].name {
+			t.Errorf("Files[%d] name=%q, want=%q", i, f.FileName, files[i].name)
+		}
+		if got := readAll(t, f); got != files[i].content {
+			t.Errorf("%s: content=%q, want=%q", f.FileName, got, files[i].content)
+		}
+	}
+}
+
+func TestSplitWriter(t *testing.T) {
+	files := []splitTestFile{
+		{"file1.txt", "file1 content"},
+		{"dir/", ""},
+		{"dir/file2.txt", "file2 content, which is longer than file1"},
+		{"file3.txt", "file3"},
+	}
+	sizes := []int64{64, 100, 1000}
+
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("size-%d", size), func(t *testing.T) {
+			volumes := make([]*bytes.Buffer, 0)
+			create := func(disk int) (io.Writer, error) {
+				if disk != len(volumes) {
+					return nil, fmt.Errorf("unexpected disk %d", disk)
+				}
+				volumes = append(volumes, new(bytes.Buffer))
+				return volumes[disk], nil
+			}
+
+			zw, err := NewSplitWriter(size, create)
+			if err != nil {
+				t.Fatalf("NewSplitWriter error=%v", err)
+			}
+			zw.Comment = "zip comment"
+			for _, file := range files {
+				fw, err := zw.Create(file.name)
+				if err != nil {
+					t.Fatalf("Writer.Create error=%v", err)
+				}
+				if _, err := fw.Write([]byte(file.content)); err != nil {
+					t.Fatalf("FileWriter.Write error=%v", err)
+				}
+				if err := fw.Close(); err != nil {
+					t.Fatalf("FileWriter.Close error=%v", err)
+				}
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			readers := make([]io.ReadSeeker, len(volumes))
+			for i, v := range volumes {
+				if int64(v.Len()) > size {
+					t.Errorf("volume %d size=%d, want<=%d", i, v.Len(), size)
+				}
+				readers[i] = bytes.NewReader(v.Bytes())
+			}
+			if size < 1000 && len(volumes) < 2 {
+				t.Errorf("number of volumes=%d, want>=2", len(volumes))
+			}
+
+			zr, err := NewSplitReader(readers)
+			if err != nil {
+				t.Fatalf("NewSplitReader error=%v", err)
+			}
+			if zr.Comment != "zip comment" {
+				t.Errorf("zip comment=%q, want=%q", zr.Comment, "zip comment")
+			}
+			testSplitFiles(t, zr, files)
+		})
+	}
+}
@@ -2,8 +2,10 @@ package zip
 
 import (
 	"bytes"
-	"errors"
 	"io"
+	"io/fs"
+	"math"
+	"strings"
 	"time"
 
 	"go-mylib/byteio"
@@ -17,6 +19,7 @@ type FileHeader struct {
 	Flags            FlagType     // general purpose flag
 	Method           MethodType   // compression method
 	ModifiedTime     time.Time    // last modification time
+	DosTime          uint32       // raw MS-DOS date (high 16 bits) and time (low 16 bits) read from the header
 	CRC32            uint32       // CRC-32 for uncompressed data
 	CompressedSize   uint32       // compressed data size
 	UncompressedSize uint32       // uncompressed data size
@@ -30,7 +33,7 @@ type FileHeader struct {
 // NewFileHeader creates a new FileHeader.
 func NewFileHeader(name string) *FileHeader {
 	return &FileHeader{
-		Method:       &MethodDeflated{DefaultCompression},
+		Method:       &MethodDeflated{Compression: DefaultCompression},
 		ModifiedTime: time.Time{},
 		FileName:     name,
 		ExtraFields:  make([]ExtraField, 0),
@@ -38,18 +41,51 @@ func NewFileHeader(name string) *FileHeader {
 }
 
 const (
+	versionDefault   int = 10 // stored file
+	versionDeflated  int = 20 // deflated file, directory or traditional encryption
+	versionZip64     int = 45 // ZIP64 extensions
+	versionGenerated int = 20 // version used to generate the file
+)
+
+// minimumVersion returns the version needed to extract the file from the features used.
+func (fh *FileHeader) minimumVersion() int {
+	version := versionDefault
+	if fh.Method.ID() == methodDeflatedID || strings.HasSuffix(fh.FileName, "/") || fh.Flags.Encrypted {
+		version = versionDeflated
+	}
+	for _, extra := range fh.ExtraFields {
+		if extra.Tag() == extraZip64Tag {
+			version = versionZip64
+		}
+	}
+	// the UTF-8 flag does not need a higher version, and unzip tools skip the files of version 63
+	return version
+}
+
+// checkLength returns an error if the length of a variable-length field exceeds the format limit.
+func checkLength(field string, n int) error {
+	if n > math.MaxUint16 {
+		return wrapError(fs.ErrInvalid, "%s length=%d exceeds %d", field, n, math.MaxUint16)
+	}
+	return nil
+}
+
+const (
+	flagEncrypted      uint16 = 0x0001 // flag for encryption
 	flagDataDescriptor uint16 = 0x0008 // flag for data descriptor
 	flagUTF8           uint16 = 0x0800 // flag for UTF-8
 )
 
 // FlagType represents flags of a zip header.
 type FlagType struct {
+	Encrypted      bool
 	DataDescriptor bool
 	UTF8           bool
 }
 
 // set sets FlagType from a zip header's flags.
 func (f *FlagType) set(flag uint16) error {
+	f.Encrypted = flag&flagEncrypted != 0
 	f.DataDescriptor = flag&flagDataDescriptor != 0
 	f.UTF8 = flag&flagUTF8 != 0
 	return nil
@@ -57,6 +93,9 @@ func (f *FlagType) set(flag uint16) error {
 
 // get returns a zip header's flags.
 func (f *FlagType) get() (flag uint16) {
+	if f.Encrypted {
+		flag |= flagEncrypted
+	}
 	if f.DataDescriptor {
 		flag |= flagDataDescriptor
 	}
@@ -132,15 +171,15 @@ type localFileHeader struct {
 // ReadFrom reads a local file header from io.Reader.
 func (h *localFileHeader) ReadFrom(r io.Reader) (int64, error) {
 	var sign [4]byte
-	if _, err := r.Read(sign[:]); err != nil {
+	if err := readFull(r, sign[:], "local file header"); err != nil {
 		return 0, err
 	}
 	if string(sign[:]) != signLocalFileHeader {
-		return 0, errors.New("invalid zip format: not found local file header signature")
+		return 0, wrapError(ErrFormat, "not found local file header signature")
 	}
 
 	var data [sizeLocalFileHeader - 4]byte
-	if _, err := io.ReadAtLeast(r, data[:], len(data)); err != nil {
+	if err := readFull(r, data[:], "local file header"); err != nil {
 		return 0, err
 	}
 
@@ -161,16 +200,16 @@ func (h *localFileHeader) ReadFrom(r io.Reader) (int64, error) {
 	byteio.GetUint16LE(rr, &extraSize)
 
 	if nameSize == 0 {
-		return 0, errors.New("invalid local file header: name length is 0")
+		return 0, wrapError(ErrFormat, "local file header: name length is 0")
 	}
 	h.fileName = make([]byte, nameSize)
-	if _, err := r.Read(h.fileName); err != nil {
+	if err := readFull(r, h.fileName, "local file header"); err != nil {
 		return 0, err
 	}
 
 	h.extraFields = make([]byte, extraSize)
 	if extraSize != 0 {
-		if _, err := r.Read(h.extraFields); err != nil {
+		if err := readFull(r, h.extraFields, "local file header"); err != nil {
 			return 0, err
 		}
 	}
@@ -194,7 +233,13 @@ func (h *localFileHeader) WriteTo(w io.Writer) (int64, error) {
 	byteio.WriteUint32LE(buf, h.uncompressedSize)
 
 	if len(h.fileName) == 0 {
-		return 0, errors.New("invalid local file header: name length is 0")
+		return 0, wrapError(ErrFormat, "local file header: name length is 0")
+	}
+	if err := checkLength("local file header: file name", len(h.fileName)); err != nil {
+		return 0, err
+	}
+	if err := checkLength("local file header: extra fields", len(h.extraFields)); err != nil {
+		return 0, err
 	}
 	byteio.WriteUint16LE(buf, uint16(len(h.fileName)))
 
@@ -208,6 +253,9 @@ func (h *localFileHeader) WriteTo(w io.Writer) (int64, error) {
 
 // copyFromHeader copies a local file header from FileHeader.
 func (h *localFileHeader) copyFromHeader(fh *FileHeader) error {
+	if err := checkLength("file name", len(fh.FileName)); err != nil {
+		return err
+	}
 	extras := new(bytes.Buffer)
 	for _, extra := range fh.ExtraFields {
 		if _, err := extra.WriteTo(extras); err != nil {
@@ -252,12 +300,13 @@ func (h *localFileHeader) copyToHeader(fh *FileHeader) error {
 	fh.Flags.set(h.flag)
 	fh.Method = method
 	fh.Method.set(h.flag)
-	fh.ModifiedTime = uint16ToDosTime(h.moddate, h.modtime)
+	fh.DosTime = uint32(h.moddate)<<16 | uint32(h.modtime)
 	fh.CRC32 = h.crc32
 	fh.CompressedSize = h.compressedSize
 	fh.UncompressedSize = h.uncompressedSize
 	fh.FileName = string(h.fileName)
 	fh.ExtraFields = extra
+	fh.ModifiedTime = fh.modifiedTime(time.UTC)
 
 	return nil
 }
@@ -285,15 +334,15 @@ type centralDirectoryHeader struct {
 // ReadFrom reads a central directory header from io.Reader.
 func (h *centralDirectoryHeader) ReadFrom(r io.Reader) (int64, error) {
 	var sign [4]byte
-	if _, err := r.Read(sign[:]); err != nil {
+	if err := readFull(r, sign[:], "central directory header"); err != nil {
 		return 0, err
 	}
 	if string(sign[:]) != signCentralDirectoryHeader {
-		return 0, errors.New("invalid zip format: not found central directory header signature")
+		return 0, wrapError(ErrFormat, "not found central directory header signature")
 	}
 
 	var data [sizeCentralDirectoryHeader - 4]byte
-	if _, err := io.ReadAtLeast(r, data[:], len(data)); err != nil {
+	if err := readFull(r, data[:], "central directory header"); err != nil {
 		return 0, err
 	}
 
@@ -320,33 +369,29 @@ func (h *centralDirectoryHeader) ReadFrom(r io.Reader) (int64, error) {
 	byteio.GetUint32LE(rr, &h.externalFileAttr)
 	byteio.GetUint32LE(rr, &h.localHeaderOffset)
 
-	if h.diskNumber != 0 {
-		return 0, errors.New("unsupport split zip file")
-	}
-
 	if nameSize == 0 {
-		return 0, errors.New("invalid central file header: name length is 0")
+		return 0, wrapError(ErrFormat, "central file header: name length is 0")
 	}
 	h.fileName = make([]byte, nameSize)
-	if _, err := r.Read(h.fileName); err != nil {
+	if err := readFull(r, h.fileName, "central directory header"); err != nil {
 		return 0, err
 	}
 
 	h.extraFields = make([]byte, extraSize)
 	if extraSize != 0 {
-		if _, err := r.Read(h.extraFields); err != nil {
+		if err := readFull(r, h.extraFields, "central directory header"); err != nil {
 			return 0, err
 		}
 	}
 
 	h.comment = make([]byte, commentSize)
 	if commentSize != 0 {
-		if _, err := r.Read(h.comment); err != nil {
+		if err := readFull(r, h.comment, "central directory header"); err != nil {
 			return 0, err
 		}
 	}
 
-	size := int64(sizeLocalFileHeader)
+	size := int64(sizeCentralDirectoryHeader)
 	size += int64(nameSize) + int64(extraSize) + int64(commentSize)
 	return size, nil
 }
@@ -354,7 +399,7 @@ func (h *centralDirectoryHeader) ReadFrom(r io.Reader) (int64, error) {
 // WriteTo writes a central directory header to io.Writer.
 func (h *centralDirectoryHeader) WriteTo(w io.Writer) (int64, error) {
 	buf := new(bytes.Buffer)
-	w.Write([]byte(signCentralDirectoryHeader))
+	buf.Write([]byte(signCentralDirectoryHeader))
 	byteio.WriteUint16LE(buf, h.generateVersion)
 	byteio.WriteUint16LE(buf, h.minimumVersion)
 	byteio.WriteUint16LE(buf, h.flag)
@@ -366,7 +411,16 @@ func (h *centralDirectoryHeader) WriteTo(w io.Writer) (int64, error) {
 	byteio.WriteUint32LE(buf, h.uncompressedSize)
 
 	if len(h.fileName) == 0 {
-		return 0, errors.New("invalid central file header: name length is 0")
+		return 0, wrapError(ErrFormat, "central file header: name length is 0")
+	}
+	if err := checkLength("central file header: file name", len(h.fileName)); err != nil {
+		return 0, err
+	}
+	if err := checkLength("central file header: extra fields", len(h.extraFields)); err != nil {
+		return 0, err
+	}
+	if err := checkLength("central file header: file comment", len(h.comment)); err != nil {
+		return 0, err
 	}
 	byteio.WriteUint16LE(buf, uint16(len(h.fileName)))
 
@@ -392,6 +446,15 @@ func (h *centralDirectoryHeader) copyFromHeader(fh *FileHeader) error {
 			return err
 		}
 	}
+	if err := checkLength("file name", len(fh.FileName)); err != nil {
+		return err
+	}
+	if err := checkLength("extra fields", extras.Len()); err != nil {
+		return withPosition(err, fh.FileName, -1)
+	}
+	if err := checkLength("file comment", len(fh.Comment)); err != nil {
+		return withPosition(err, fh.FileName, -1)
+	}
 
 	h.generateVersion = uint16(fh.GenerateOS)<<8 | uint16(fh.GenerateVersion)&0x00ff
 	h.minimumVersion = uint16(fh.MinimumVersion) & 0x00ff
@@ -428,12 +491,13 @@ func (h *centralDirectoryHeader) copyToHeader(fh *FileHeader) error {
 	fh.Flags.set(h.flag)
 	fh.Method = method
 	fh.Method.set(h.flag)
-	fh.ModifiedTime = uint16ToDosTime(h.moddate, h.modtime)
+	fh.DosTime = uint32(h.moddate)<<16 | uint32(h.modtime)
 	fh.CRC32 = h.crc32
 	fh.CompressedSize = h.compressedSize
 	fh.UncompressedSize = h.uncompressedSize
 	fh.FileName = string(h.fileName)
 	fh.ExtraFields = extra
+	fh.ModifiedTime = fh.modifiedTime(time.UTC)
 	fh.InternalFileAttr = h.internalFileAttr
 	fh.ExternalFileAttr = h.externalFileAttr
 	fh.Comment = string(h.comment)
@@ -452,7 +516,7 @@ type dataDescriptor struct {
 func (d *dataDescriptor) ReadFrom(r io.Reader) (int64, error) {
 	var buf [sizeDataDescriptor]byte
 	size := len(buf) - 4
-	if _, err := io.ReadAtLeast(r, buf[:size], size); err != nil {
+	if err := readFull(r, buf[:size], "data descriptor"); err != nil {
 		return 0, err
 	}
 
@@ -464,7 +528,7 @@ func (d *dataDescriptor) ReadFrom(r io.Reader) (int64, error) {
 	if string(sign[:]) == signDataDescriptor {
 		// load additional data
 		size += 4
-		if _, err := io.ReadAtLeast(r, buf[size-4:], 4); err != nil {
+		if err := readFull(r, buf[size-4:], "data descriptor"); err != nil {
 			return 0, err
 		}
 		rr = bytes.NewReader(buf[4:])
@@ -507,15 +571,15 @@ type endCentralDirectory struct {
 // ReadFrom reads an end of central directory record from io.Reader.
 func (e *endCentralDirectory) ReadFrom(r io.Reader) (int64, error) {
 	var sign [4]byte
-	if _, err := r.Read(sign[:]); err != nil {
+	if err := readFull(r, sign[:], "end of central directory record"); err != nil {
 		return 0, err
 	}
 	if string(sign[:]) != signEndCentralDirectory {
-		return 0, errors.New("invalid zip format: not found end of central directory signature")
+		return 0, wrapError(ErrFormat, "not found end of central directory signature")
 	}
 
 	var buf [sizeEndCentralDirectory - 4]byte
-	if _, err := r.Read(buf[:]); err != nil {
+	if err := readFull(r, buf[:], "end of central directory record"); err != nil {
 		return 0, err
 	}
 
@@ -531,13 +595,9 @@ func (e *endCentralDirectory) ReadFrom(r io.Reader) (int64, error) {
 	byteio.GetUint32LE(rr, &e.offsetCentralDirectory)
 	byteio.GetUint16LE(rr, &commentSize)
 
-	if e.numberOfDisk != 0 || e.numberOfStartDirDisk != 0 || e.numberOfEntriesThisDisk != e.numberOfEntries {
-		return 0, errors.New("unsupport split zip file")
-	}
-
 	e.comment = make([]byte, commentSize)
 	if commentSize != 0 {
-		if _, err := r.Read(e.comment); err != nil {
+		if err := readFull(r, e.comment, "end of central directory record"); err != nil {
 			return 0, err
 		}
 	}
@@ -548,8 +608,11 @@ func (e *endCentralDirectory) ReadFrom(r io.Reader) (int64, error) {
 
 // WriteTo writes an end of central directory record to io.Writer.
 func (e *endCentralDirectory) WriteTo(w io.Writer) (int64, error) {
+	if err := checkLength("end of central directory: comment", len(e.comment)); err != nil {
+		return 0, err
+	}
 	buf := new(bytes.Buffer)
-	w.Write([]byte(signEndCentralDirectory))
+	buf.Write([]byte(signEndCentralDirectory))
 	byteio.WriteUint16LE(buf, e.numberOfDisk)
 	byteio.WriteUint16LE(buf, e.numberOfStartDirDisk)
 	byteio.WriteUint16LE(buf, e.numberOfEntriesThisDisk)
@@ -563,8 +626,20 @@ func (e *endCentralDirectory) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
-// uint16ToDosTime converts a date/time in uint16 to a MS-DOS time.
-func uint16ToDosTime(dates uint16, times uint16) time.Time {
+// readFull reads exactly len(buf) bytes of the header from r.
+// A short read is reported as ErrFormat, because the header is truncated.
+func readFull(r io.Reader, buf []byte, header string) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return wrapError(ErrFormat, "%s is truncated", header)
+		}
+		return err
+	}
+	return nil
+}
+
+// uint16ToDosTime converts a date/time in uint16 to a MS-DOS time in loc.
+func uint16ToDosTime(dates uint16, times uint16, loc *time.Location) time.Time {
 	if dates == 0 && times == 0 {
 		return time.Time{}
 	}
@@ -579,16 +654,22 @@ func uint16ToDosTime(dates uint16, times uint16) time.Time {
 	year += 1980
 	secs *= 2
 
-	return time.Date(year, time.Month(monh), days, hour, mins, secs, 0, time.UTC)
+	return time.Date(year, time.Month(monh), days, hour, mins, secs, 0, loc)
 }
 
 // uint16FromDosTime converts a MS-DOS time to a date/time in uint16.
+// The time is converted in its location, and clamped to the range of MS-DOS time.
 func uint16FromDosTime(t time.Time) (dates uint16, times uint16) {
-	if t.IsZero() || t.Year() < 1980 {
+	if t.IsZero() {
 		return 0, 0
 	}
 
-	t = t.UTC()
+	switch {
+	case t.Year() < dosMinYear:
+		t = time.Date(dosMinYear, 1, 1, 0, 0, 0, 0, t.Location())
+	case t.Year() > dosMaxYear:
+		t = time.Date(dosMaxYear, 12, 31, 23, 59, 58, 0, t.Location())
+	}
 	dates |= (uint16(t.Year()) - 1980) << 9
 	dates |= uint16(t.Month()) << 5
 	dates |= uint16(t.Day())
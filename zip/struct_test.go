@@ -78,8 +78,9 @@ func Test_localFileHeader(t *testing.T) {
 			expect: &FileHeader{
 				MinimumVersion:   0x0014,
 				Flags:            FlagType{},
-				Method:           &MethodDeflated{DefaultCompression},
+				Method:           &MethodDeflated{Compression: DefaultCompression},
 				ModifiedTime:     time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC),
+				DosTime:          0x54a6645c,
 				CRC32:            0x01020304,
 				CompressedSize:   0x12345678,
 				UncompressedSize: 0xabcdef09,
@@ -106,8 +107,9 @@ func Test_localFileHeader(t *testing.T) {
 			expect: &FileHeader{
 				MinimumVersion:   0x0014,
 				Flags:            FlagType{DataDescriptor: true},
-				Method:           &MethodDeflated{DefaultCompression},
+				Method:           &MethodDeflated{Compression: DefaultCompression},
 				ModifiedTime:     time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC),
+				DosTime:          0x54a6645c,
 				CRC32:            0x01020304,
 				CompressedSize:   0x12345678,
 				UncompressedSize: 0xabcdef09,
@@ -182,8 +184,9 @@ func Test_centralDirectoryHeader(t *testing.T) {
 		GenerateVersion:  0x20,
 		GenerateOS:       OS_MSDOS,
 		Flags:            FlagType{DataDescriptor: true},
-		Method:           &MethodDeflated{DefaultCompression},
+		Method:           &MethodDeflated{Compression: DefaultCompression},
 		ModifiedTime:     time.Date(2022, time.Month(5), 6, 12, 34, 56, 0, time.UTC),
+		DosTime:          0x54a6645c,
 		CRC32:            0x01020304,
 		CompressedSize:   0x12345678,
 		UncompressedSize: 0xabcdef09,
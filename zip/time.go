@@ -0,0 +1,81 @@
+package zip
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	dosMinYear = 1980 // minimum year of MS-DOS time
+	dosMaxYear = 2107 // maximum year of MS-DOS time
+)
+
+// modifiedTime returns the modification time from the most accurate source:
+// NTFS extra field, extended timestamp extra field, then MS-DOS time in loc.
+// If loc is nil, UTC is used.
+func (fh *FileHeader) modifiedTime(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var ext time.Time
+	for _, extra := range fh.ExtraFields {
+		switch e := extra.(type) {
+		case *ExtraNTFS:
+			if !e.Mtime.IsZero() {
+				return e.Mtime.In(loc)
+			}
+		case *ExtraExtendedTimestamp:
+			if ext.IsZero() {
+				ext = e.Mtime
+			}
+		}
+	}
+	if !ext.IsZero() {
+		return ext.In(loc)
+	}
+	return uint16ToDosTime(uint16(fh.DosTime>>16), uint16(fh.DosTime), loc)
+}
+
+// setModifiedTime prepares ModifiedTime for writing.
+// MS-DOS time is written in loc (UTC if loc is nil).
+// If MS-DOS time can not represent ModifiedTime exactly, it is also stored in the timestamp extra fields:
+// the existing NTFS or extended timestamp extra field is updated,
+// or a new NTFS or extended timestamp extra field is added.
+func (fh *FileHeader) setModifiedTime(loc *time.Location) {
+	if fh.ModifiedTime.IsZero() {
+		return
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := fh.ModifiedTime.In(loc)
+	fh.ModifiedTime = t
+
+	found := false
+	extras := make([]ExtraField, 0, len(fh.ExtraFields)+1)
+	for _, extra := range fh.ExtraFields {
+		switch e := extra.(type) {
+		case *ExtraNTFS:
+			copied := *e
+			copied.Mtime = t
+			extra, found = &copied, true
+		case *ExtraExtendedTimestamp:
+			copied := *e
+			copied.Mtime = t
+			extra, found = &copied, true
+		}
+		extras = append(extras, extra)
+	}
+
+	switch {
+	case found:
+		// updated
+	case t.Nanosecond() != 0 || t.Unix() < math.MinInt32 || t.Unix() > math.MaxInt32:
+		// extended timestamp has no sub-second and is 32-bit UNIX time
+		extras = append(extras, &ExtraNTFS{Mtime: t, Atime: t, Ctime: t})
+	case loc != time.UTC || t.Second()%2 != 0 || t.Year() < dosMinYear || t.Year() > dosMaxYear:
+		extras = append(extras, &ExtraExtendedTimestamp{Mtime: t})
+	}
+	fh.ExtraFields = extras
+}
@@ -0,0 +1,102 @@
+package zip
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go-mylib/buffer"
+)
+
+func TestModifiedTime(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name    string
+		mtime   time.Time
+		wloc    *time.Location // writer location
+		rloc    *time.Location // reader location
+		want    time.Time
+		dostime uint32
+	}{
+		{
+			name:    "dos",
+			mtime:   time.Date(2022, 5, 6, 12, 34, 56, 0, time.UTC),
+			want:    time.Date(2022, 5, 6, 12, 34, 56, 0, time.UTC),
+			dostime: 0x54a6645c,
+		},
+		{
+			name:    "dos-location",
+			mtime:   time.Date(2022, 5, 6, 12, 34, 56, 0, time.UTC),
+			rloc:    tokyo,
+			want:    time.Date(2022, 5, 6, 12, 34, 56, 0, tokyo),
+			dostime: 0x54a6645c,
+		},
+		{
+			name:    "odd-seconds",
+			mtime:   time.Date(2022, 5, 6, 12, 34, 57, 0, time.UTC),
+			want:    time.Date(2022, 5, 6, 12, 34, 57, 0, time.UTC),
+			dostime: 0x54a6645c,
+		},
+		{
+			name:    "nanoseconds",
+			mtime:   time.Date(2022, 5, 6, 12, 34, 56, 123456700, time.UTC),
+			rloc:    tokyo,
+			want:    time.Date(2022, 5, 6, 12, 34, 56, 123456700, time.UTC),
+			dostime: 0x54a6645c,
+		},
+		{
+			name:    "writer-location",
+			mtime:   time.Date(2022, 5, 6, 3, 34, 56, 0, time.UTC),
+			wloc:    tokyo,
+			want:    time.Date(2022, 5, 6, 3, 34, 56, 0, time.UTC),
+			dostime: 0x54a6645c,
+		},
+		{
+			name:    "before-1980",
+			mtime:   time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC),
+			want:    time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC),
+			dostime: 0x00210000,
+		},
+		{
+			name:    "after-2107",
+			mtime:   time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+			dostime: 0xff9fbf7d,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{Location: tt.wloc})
+			if err != nil {
+				t.Fatalf("NewWriterWithOptions error=%v", err)
+			}
+			fh := NewFileHeader("file.txt")
+			fh.ModifiedTime = tt.mtime
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if err := fw.Close(); err != nil {
+				t.Fatalf("FileWriter.Close error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), &ReaderOptions{Location: tt.rloc})
+			if err != nil {
+				t.Fatalf("NewReaderWithOptions error=%v", err)
+			}
+			f := zr.Files[0]
+			if !f.ModifiedTime.Equal(tt.want) {
+				t.Fatalf("ModifiedTime=%v, want=%v", f.ModifiedTime, tt.want)
+			}
+			if f.DosTime != tt.dostime {
+				t.Fatalf("DosTime=%#08x, want=%#08x", f.DosTime, tt.dostime)
+			}
+		})
+	}
+}
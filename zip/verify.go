@@ -0,0 +1,241 @@
+package zip
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// VerifyReport represents the result of Reader.Verify.
+type VerifyReport struct {
+	Entries []*EntryReport // report of each file (same order as the central directory)
+	Errors  []error        // problems of the whole archive
+}
+
+// OK returns whether no problem is found.
+func (r *VerifyReport) OK() bool {
+	if len(r.Errors) != 0 {
+		return false
+	}
+	for _, e := range r.Entries {
+		if !e.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// EntryReport represents the verification result of a file.
+type EntryReport struct {
+	Name   string  // file name
+	Offset int64   // offset of the local file header
+	Size   int64   // size of the entry (local file header, data, data descriptor)
+	Errors []error // problems of the file
+}
+
+// OK returns whether no problem is found.
+func (e *EntryReport) OK() bool {
+	return len(e.Errors) == 0
+}
+
+// Verify tests the integrity of all files in the zip archive, like `unzip -t`.
+// It compares local and central headers, decompresses the data and checks CRC-32,
+// and detects overlapping or out-of-bounds data, duplicate names and unused data.
+// All problems are collected in the report instead of stopping at the first problem.
+// The returned error is not nil only if ctx is done or the central directory can not be read.
+func (r *Reader) Verify(ctx context.Context) (*VerifyReport, error) {
+	files, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+	report := &VerifyReport{
+		Entries: make([]*EntryReport, len(files)),
+		Errors:  make([]error, 0),
+	}
+
+	names := make(map[string]int)
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e := &EntryReport{
+			Name:   f.FileName,
+			Offset: f.offset,
+			Errors: make([]error, 0),
+		}
+		report.Entries[i] = e
+
+		if j, ok := names[f.FileName]; ok {
+			e.Errors = append(e.Errors, wrapError(ErrFormat, "duplicate name: same as entry #%d", j))
+		} else {
+			names[f.FileName] = i
+		}
+
+		if err := r.verifyFile(ctx, f, e); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			e.Errors = append(e.Errors, err)
+		}
+	}
+
+	r.verifyLayout(report)
+	return report, nil
+}
+
+// verifyFile verifies the file, and updates the entry size.
+// Problems are added to the report, and an error stops the verification of the file.
+func (r *Reader) verifyFile(ctx context.Context, f *File, e *EntryReport) error {
+	if f.offset < r.starts[0] || f.offset >= r.dirOffset {
+		return wrapError(ErrFormat, "local file header offset=%d is out of range", f.offset)
+	}
+	if _, err := f.r.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	h := new(localFileHeader)
+	n, err := h.ReadFrom(f.r)
+	if err != nil {
+		return fmt.Errorf("local file header: %w", err)
+	}
+	local := new(FileHeader)
+	if err := h.copyToHeader(local); err != nil {
+		return fmt.Errorf("local file header: %w", err)
+	}
+	e.Errors = append(e.Errors, compareHeaders(local, &f.FileHeader)...)
+
+	start := f.offset + n
+	end := start + int64(f.CompressedSize)
+	if end > r.dirOffset {
+		return wrapError(ErrFormat, "file data (offset=%d, size=%d) is out of range", start, f.CompressedSize)
+	}
+
+	if f.Flags.DataDescriptor {
+		if _, err := f.r.Seek(end, io.SeekStart); err != nil {
+			return err
+		}
+		dd := new(dataDescriptor)
+		ddsize, err := dd.ReadFrom(f.r)
+		if err != nil {
+			return fmt.Errorf("data descriptor: %w", err)
+		}
+		if dd.crc32 != f.CRC32 {
+			e.Errors = append(e.Errors, wrapError(ErrFormat, "data descriptor CRC-32=%08x, central=%08x", dd.crc32, f.CRC32))
+		}
+		if dd.compressedSize != f.CompressedSize {
+			e.Errors = append(e.Errors, wrapError(ErrFormat, "data descriptor compressed size=%d, central=%d", dd.compressedSize, f.CompressedSize))
+		}
+		if dd.uncompressedSize != f.UncompressedSize {
+			e.Errors = append(e.Errors, wrapError(ErrFormat, "data descriptor uncompressed size=%d, central=%d", dd.uncompressedSize, f.UncompressedSize))
+		}
+		end += ddsize
+	}
+	if end > r.dirOffset {
+		return wrapError(ErrFormat, "data descriptor (offset=%d) is out of range", end)
+	}
+	e.Size = end - f.offset
+
+	// decompress and check CRC-32
+	if _, err := f.r.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	cr := &contextReader{ctx: ctx, r: io.LimitReader(f.r, int64(f.CompressedSize))}
+	dr, err := f.Method.newDecompressor(cr)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+
+	hash := crc32.NewIEEE()
+	size, err := io.Copy(hash, dr)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	if size != int64(f.UncompressedSize) {
+		e.Errors = append(e.Errors, wrapError(ErrChecksum, "uncompressed size=%d, central=%d", size, f.UncompressedSize))
+	}
+	if hash.Sum32() != f.CRC32 {
+		e.Errors = append(e.Errors, wrapError(ErrChecksum, "CRC-32=%08x, central=%08x", hash.Sum32(), f.CRC32))
+	}
+	return nil
+}
+
+// verifyLayout detects overlapping entries and unused data between entries.
+func (r *Reader) verifyLayout(report *VerifyReport) {
+	entries := make([]*EntryReport, 0, len(report.Entries))
+	for _, e := range report.Entries {
+		if e.Size != 0 {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Offset < entries[j].Offset
+	})
+
+	pos := r.starts[0]
+	if len(r.starts) > 1 {
+		pos += int64(len(signDataDescriptor)) // spanning signature
+	}
+	var prev *EntryReport
+	for _, e := range entries {
+		switch {
+		case e.Offset < pos && prev != nil:
+			e.Errors = append(e.Errors, wrapError(ErrFormat, "entry data overlaps with %q", prev.Name))
+		case e.Offset > pos:
+			report.Errors = append(report.Errors, wrapError(ErrFormat, "unused data (offset=%d, size=%d) before %q", pos, e.Offset-pos, e.Name))
+		}
+		if end := e.Offset + e.Size; end > pos {
+			pos = end
+			prev = e
+		}
+	}
+	if pos < r.dirOffset {
+		report.Errors = append(report.Errors, wrapError(ErrFormat, "unused data (offset=%d, size=%d) before central directory", pos, r.dirOffset-pos))
+	}
+}
+
+// compareHeaders compares the local file header with the central directory header.
+func compareHeaders(local, central *FileHeader) []error {
+	errs := make([]error, 0)
+	if local.FileName != central.FileName {
+		errs = append(errs, wrapError(ErrFormat, "local name=%q, central=%q", local.FileName, central.FileName))
+	}
+	if local.Method.ID() != central.Method.ID() {
+		errs = append(errs, wrapError(ErrFormat, "local method=%d, central=%d", local.Method.ID(), central.Method.ID()))
+	}
+	if lflag, cflag := local.Flags.get()|local.Method.get(), central.Flags.get()|central.Method.get(); lflag != cflag {
+		errs = append(errs, wrapError(ErrFormat, "local flags=%#04x, central=%#04x", lflag, cflag))
+	}
+
+	if local.Flags.DataDescriptor && local.CRC32 == 0 && local.CompressedSize == 0 && local.UncompressedSize == 0 {
+		// values are in the data descriptor
+		return errs
+	}
+	if local.CRC32 != central.CRC32 {
+		errs = append(errs, wrapError(ErrFormat, "local CRC-32=%08x, central=%08x", local.CRC32, central.CRC32))
+	}
+	if local.CompressedSize != central.CompressedSize {
+		errs = append(errs, wrapError(ErrFormat, "local compressed size=%d, central=%d", local.CompressedSize, central.CompressedSize))
+	}
+	if local.UncompressedSize != central.UncompressedSize {
+		errs = append(errs, wrapError(ErrFormat, "local uncompressed size=%d, central=%d", local.UncompressedSize, central.UncompressedSize))
+	}
+	return errs
+}
+
+// contextReader implements io.Reader that stops reading when the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read implements the standard Read interface.
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
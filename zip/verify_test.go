@@ -0,0 +1,148 @@
+package zip
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"go-mylib/buffer"
+)
+
+func TestReaderVerify(t *testing.T) {
+	buf := new(buffer.Buffer)
+	zw, err := NewWriter(buffer.NewWriter(buf))
+	if err != nil {
+		t.Fatalf("NewWriter error=%v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Writer.Create error=%v", err)
+		}
+		if _, err := fw.Write([]byte("content of " + name)); err != nil {
+			t.Fatalf("FileWriter.Write error=%v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+	src := buf.Bytes()
+
+	// offsets of the central directory headers
+	cdirs := make([]int, 0)
+	for i := 0; i+4 <= len(src); i++ {
+		if string(src[i:i+4]) == signCentralDirectoryHeader {
+			cdirs = append(cdirs, i)
+		}
+	}
+	if len(cdirs) != 2 {
+		t.Fatalf("central directory headers=%d, want=%d", len(cdirs), 2)
+	}
+
+	tests := []struct {
+		name   string
+		modify func([]byte)
+		entry  map[int]string // entry index -> error message
+		global string         // archive error message
+	}{
+		{
+			name:   "valid",
+			modify: func(b []byte) {},
+		},
+		{
+			name: "broken-crc",
+			modify: func(b []byte) {
+				binary.LittleEndian.PutUint32(b[cdirs[1]+16:], 0x12345678)
+			},
+			entry: map[int]string{1: "CRC-32"},
+		},
+		{
+			name: "broken-data",
+			modify: func(b []byte) {
+				b[sizeLocalFileHeader+len("a.txt")] ^= 0xff
+			},
+			entry: map[int]string{0: "decompress"},
+		},
+		{
+			name: "duplicate-name",
+			modify: func(b []byte) {
+				copy(b[cdirs[1]+sizeCentralDirectoryHeader:], "a.txt")
+			},
+			entry: map[int]string{1: "duplicate name"},
+		},
+		{
+			name: "overlapping",
+			modify: func(b []byte) {
+				binary.LittleEndian.PutUint32(b[cdirs[1]+42:], 0)
+				copy(b[cdirs[1]+sizeCentralDirectoryHeader:], "a.txt")
+			},
+			entry:  map[int]string{1: "overlaps"},
+			global: "unused data",
+		},
+		{
+			name: "out-of-range",
+			modify: func(b []byte) {
+				binary.LittleEndian.PutUint32(b[cdirs[1]+42:], uint32(cdirs[0]))
+			},
+			entry: map[int]string{1: "out of range"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{}, src...)
+			tt.modify(data)
+
+			zr, err := NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			report, err := zr.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify error=%v", err)
+			}
+
+			if ok := len(tt.entry) == 0 && tt.global == ""; report.OK() != ok {
+				t.Errorf("OK=%v, want=%v", report.OK(), ok)
+			}
+			for i, e := range report.Entries {
+				msg, ok := tt.entry[i]
+				if !ok {
+					if !e.OK() {
+						t.Errorf("entry#%d errors=%v, want none", i, e.Errors)
+					}
+					continue
+				}
+				if !containsError(e.Errors, msg) {
+					t.Errorf("entry#%d errors=%v, want %q", i, e.Errors, msg)
+				}
+			}
+			if tt.global != "" && !containsError(report.Errors, tt.global) {
+				t.Errorf("errors=%v, want %q", report.Errors, tt.global)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(src))
+		if err != nil {
+			t.Fatalf("NewReader error=%v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := zr.Verify(ctx); err != context.Canceled {
+			t.Errorf("Verify error=%v, want=%v", err, context.Canceled)
+		}
+	})
+}
+
+func containsError(errs []error, msg string) bool {
+	for _, err := range errs {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,30 +1,138 @@
 package zip
 
 import (
-	"errors"
-	"fmt"
+	"bytes"
 	"hash"
 	"hash/crc32"
 	"io"
 	"io/fs"
+	"math"
 	"strings"
+	"time"
 )
 
 // Writer creates a zip file.
 type Writer struct {
 	w    io.WriteSeeker
+	bw   *bufferedWriter // buffered writer (nil if not buffered)
+	vw   *volumeWriter   // split zip file writer (nil if not split)
 	dirs []*centralDirectoryHeader
 	pre  *fileWriter
 
+	repro *reproducible  // reproducible mode (nil if disabled)
+	loc   *time.Location // location of MS-DOS time (nil means UTC)
+	spill *dirSpill      // spilled central directory headers (nil if disabled)
+	names *nameSet       // written file names (nil if duplicates are allowed)
+	count int            // number of entries
+
+	adaptive bool // store deflated files that do not compress
+
 	Comment string
 }
 
+// WriterOptions represents options of zip.Writer.
+type WriterOptions struct {
+	// Prefix is written before the zip archive (e.g. self-extracting stub, shell script).
+	// Offsets in the zip archive include the prefix size.
+	Prefix io.Reader
+
+	// Reproducible writes the same archive for the same file names and contents.
+	// The modified time, the attributes, the versions and the OS are normalized,
+	// the timestamp extra fields are removed, and files are sorted by name.
+	// Files are kept in memory until Close is called.
+	Reproducible bool
+
+	// Epoch is the modified time of all files in reproducible mode.
+	// If zero, SOURCE_DATE_EPOCH environment variable is used,
+	// or 1980-01-01 00:00:00 UTC if it is not set.
+	// It is clamped to the MS-DOS time range and rounded down to even seconds in UTC.
+	Epoch time.Time
+
+	// Location is the time zone of MS-DOS time in the headers.
+	// If nil, UTC is used.
+	Location *time.Location
+
+	// Spill stores the central directory headers in a temporary file instead of memory,
+	// and reads them back at Close. The temporary file is removed at Close.
+	// The file names are still kept in memory for the duplicate checks
+	// unless AllowDuplicates is also set.
+	Spill bool
+
+	// SpillFile is used instead of a temporary file if Spill is true and SpillFile is not nil.
+	// The headers are stored from the current position. SpillFile is not closed.
+	SpillFile io.ReadWriteSeeker
+
+	// AllowDuplicates disables the checks of the file names against the names written so far:
+	// exact duplicates, file and directory conflicts (e.g. "a" and "a/b"),
+	// and names that collide after Unicode normalization and case folding.
+	// The checks keep all written names in memory.
+	AllowDuplicates bool
+
+	// AdaptiveStore stores deflated files instead if deflate does not help.
+	// Files with an extension of an already compressed format (e.g. ".jpg", ".zip") are stored,
+	// and the others are decided by deflating the first 64 KiB before the file header is written.
+	// The chosen method is set to the FileHeader.
+	AdaptiveStore bool
+
+	// BufferSize is the size of the output buffer. The buffered data is written at Flush and Close.
+	// If 0, 32 KiB is used. If negative, the output is not buffered.
+	BufferSize int
+}
+
 // NewWriter returns zip.Writer that writes to io.WriteSeeker.
 func NewWriter(w io.WriteSeeker) (*Writer, error) {
-	return &Writer{
+	return NewWriterWithOptions(w, nil)
+}
+
+// NewWriterWithOptions returns zip.Writer that writes to io.WriteSeeker with options.
+// If opts is nil, the default options are used.
+func NewWriterWithOptions(w io.WriteSeeker, opts *WriterOptions) (*Writer, error) {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+
+	zw := &Writer{
 		w:    w,
 		dirs: make([]*centralDirectoryHeader, 0),
-	}, nil
+		loc:  opts.Location,
+
+		adaptive: opts.AdaptiveStore,
+	}
+	if !opts.AllowDuplicates {
+		zw.names = newNameSet()
+	}
+	if opts.Reproducible {
+		repro, err := newReproducible(opts.Epoch)
+		if err != nil {
+			return nil, err
+		}
+		zw.repro = repro
+	}
+
+	if opts.Prefix != nil {
+		if _, err := io.Copy(w, opts.Prefix); err != nil {
+			return nil, err
+		}
+	}
+	if opts.BufferSize >= 0 {
+		size := opts.BufferSize
+		if size == 0 {
+			size = defaultBufferSize
+		}
+		bw, err := newBufferedWriter(w, size)
+		if err != nil {
+			return nil, err
+		}
+		zw.w, zw.bw = bw, bw
+	}
+	if opts.Spill {
+		spill, err := newDirSpill(opts.SpillFile)
+		if err != nil {
+			return nil, err
+		}
+		zw.spill = spill
+	}
+	return zw, nil
 }
 
 // Create returns io.WriteCloser that creates a file with name.
@@ -43,9 +151,6 @@ func (w *Writer) CreateFromHeader(fh *FileHeader) (io.WriteCloser, error) {
 	}
 
 	// update file header
-	fh.MinimumVersion = 20
-	fh.GenerateVersion = 20
-	fh.GenerateOS = OS_MSDOS
 	fh.CRC32 = 0            // update by FileWriter
 	fh.CompressedSize = 0   // update by FileWriter
 	fh.UncompressedSize = 0 // update by FileWriter
@@ -58,30 +163,86 @@ func (w *Writer) CreateFromHeader(fh *FileHeader) (io.WriteCloser, error) {
 		fh.Method = &MethodStore{}
 	}
 	if ok := fs.ValidPath(fh.FileName[:namesize]); !ok {
-		return nil, fmt.Errorf("file name is invalid: %q", fh.FileName)
+		return nil, wrapError(ErrInsecurePath, "file name %q", fh.FileName)
 	}
-
-	offset, err := w.w.Seek(0, io.SeekCurrent)
-	if err != nil {
+	if fh.Flags.Encrypted {
+		return nil, wrapError(ErrUnsupported, "writing encrypted file %q", fh.FileName)
+	}
+	if err := w.checkName(fh.FileName); err != nil {
 		return nil, err
 	}
+	if w.vw != nil {
+		// split zip file can not rewrite the file header
+		fh.Flags.DataDescriptor = true
+	}
+	if w.adaptive && fh.Method.ID() == methodDeflatedID && isIncompressible(fh.FileName) {
+		fh.Method = &MethodStore{}
+	}
+	if w.repro != nil {
+		// the epoch is already in the MS-DOS time range, and no timestamp extra is added
+		w.repro.normalize(fh)
+	} else {
+		fh.setModifiedTime(w.loc)
+	}
+	fh.MinimumVersion = fh.minimumVersion()
+	fh.GenerateVersion = versionGenerated
+	if fh.GenerateVersion < fh.MinimumVersion {
+		fh.GenerateVersion = fh.MinimumVersion
+	}
 
 	h := &centralDirectoryHeader{}
 	if err := h.copyFromHeader(fh); err != nil {
 		return nil, err
 	}
-	h.localHeaderOffset = uint32(offset)
+	out, err := w.entryWriter(h)
+	if err != nil {
+		return nil, err
+	}
 
 	fw := &fileWriter{
-		w: w.w,
-		h: h,
+		zw: w,
+		w:  out,
+		h:  h,
+		fh: fh,
+	}
+	if w.adaptive {
+		fw.probe = newProbe(fh)
 	}
-
-	w.dirs = append(w.dirs, h)
 	w.pre = fw
 	return fw, nil
 }
 
+// CreateSymlink creates a symbolic link entry with name that points to target.
+// The link target is stored as the file content.
+// If the previous io.WriteCloser has not called Close, it is forced to close.
+func (w *Writer) CreateSymlink(name, target string) error {
+	return w.createSymlinkFromHeader(NewFileHeader(name), target)
+}
+
+// createSymlinkFromHeader creates a symbolic link entry with FileHeader.
+func (w *Writer) createSymlinkFromHeader(fh *FileHeader, target string) error {
+	if target == "" {
+		return wrapError(fs.ErrInvalid, "symbolic link target is empty: %q", fh.FileName)
+	}
+	if strings.HasSuffix(fh.FileName, "/") {
+		return wrapError(fs.ErrInvalid, "symbolic link name is a directory: %q", fh.FileName)
+	}
+	if !fh.IsSymlink() {
+		fh.GenerateOS = OS_UNIX
+		fh.SetMode(fs.ModeSymlink | 0777)
+	}
+	fh.Method = &MethodStore{}
+
+	fw, err := w.CreateFromHeader(fh)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, target); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
 // Copy copies the zip.File to the writer.
 // This method does not modify the File argument.
 // If the previous io.WriteCloser has not called Close, it is forced to close.
@@ -102,29 +263,46 @@ func (w *Writer) CopyFromReader(fh *FileHeader, r io.Reader) error {
 	if err := w.closePreviousFile(); err != nil {
 		return err
 	}
-
-	offset, err := w.w.Seek(0, io.SeekCurrent)
-	if err != nil {
+	if err := w.checkName(fh.FileName); err != nil {
 		return err
 	}
 
+	copied := *fh
+	fh = &copied
+	if w.repro != nil {
+		// the epoch is already in the MS-DOS time range, and no timestamp extra is added
+		w.repro.normalize(fh)
+	} else {
+		fh.setModifiedTime(w.loc)
+	}
+	if version := fh.minimumVersion(); fh.MinimumVersion < version {
+		// the copied data may use features unknown to this package
+		fh.MinimumVersion = version
+	}
+	if fh.GenerateVersion < fh.MinimumVersion {
+		fh.GenerateVersion = fh.MinimumVersion
+	}
+
 	h := &centralDirectoryHeader{}
 	if err := h.copyFromHeader(fh); err != nil {
 		return err
 	}
-	h.localHeaderOffset = uint32(offset)
+	out, err := w.entryWriter(h)
+	if err != nil {
+		return err
+	}
 
 	// write local file header
 	lh := &localFileHeader{}
 	if err := lh.copyFromHeader(fh); err != nil {
 		return err
 	}
-	if _, err := lh.WriteTo(w.w); err != nil {
+	if _, err := lh.WriteTo(out); err != nil {
 		return err
 	}
 
 	// write raw payload
-	if _, err := io.Copy(w.w, r); err != nil {
+	if _, err := io.Copy(out, r); err != nil {
 		return err
 	}
 
@@ -135,12 +313,16 @@ func (w *Writer) CopyFromReader(fh *FileHeader, r io.Reader) error {
 			compressedSize:   fh.CompressedSize,
 			uncompressedSize: fh.UncompressedSize,
 		}
-		if _, err := dd.WriteTo(w.w); err != nil {
+		if _, err := dd.WriteTo(out); err != nil {
 			return err
 		}
 	}
 
-	w.dirs = append(w.dirs, h)
+	// the entry is added after the data is written successfully
+	if err := w.addName(fh.FileName); err != nil {
+		return err
+	}
+	w.addEntry(h)
 	w.pre = nil
 	return nil
 }
@@ -148,21 +330,149 @@ func (w *Writer) CopyFromReader(fh *FileHeader, r io.Reader) error {
 // Close flushes the write data and closes zip.Writer.
 // If the previous FileWriter has not called Close, it is forced to close.
 func (w *Writer) Close() error {
+	if w.spill != nil {
+		defer w.spill.close()
+	}
+	if err := checkLength("archive comment", len(w.Comment)); err != nil {
+		return err
+	}
 	if err := w.closePreviousFile(); err != nil {
 		return err
 	}
-	return w.writeCentralDirectories()
+	if w.repro != nil {
+		if err := w.repro.flush(w); err != nil {
+			return err
+		}
+	}
+	if err := w.writeCentralDirectories(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.vw != nil {
+		return w.vw.Close()
+	}
+	return nil
+}
+
+// Flush writes the buffered data to the underlying writer.
+// The data held by the compressor of the open FileWriter is not written.
+func (w *Writer) Flush() error {
+	if w.bw == nil {
+		return nil
+	}
+	return w.bw.Flush()
 }
 
 // closePreviousFile closes the previous FileWriter.
+// In spill mode, the completed central directory headers are moved to the spill.
 func (w *Writer) closePreviousFile() (err error) {
 	if w.pre != nil && !w.pre.IsClosed() {
 		err = w.pre.Close()
 		w.pre = nil
 	}
+	if err == nil && w.spill != nil {
+		err = w.spill.add(w.dirs)
+		w.dirs = w.dirs[:0]
+	}
 	return err
 }
 
+// eachDir calls fn for each central directory header in the order of entries.
+func (w *Writer) eachDir(fn func(dir *centralDirectoryHeader) error) error {
+	if w.spill != nil {
+		if err := w.spill.each(fn); err != nil {
+			return err
+		}
+	}
+	for _, dir := range w.dirs {
+		if err := fn(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkName checks the file name against the names written so far.
+// The name is added by addName after the entry is written, so that a failed entry can be retried.
+func (w *Writer) checkName(name string) error {
+	if w.names == nil {
+		return nil
+	}
+	return w.names.check(name)
+}
+
+// addName checks the file name against the names written so far, and adds it.
+func (w *Writer) addName(name string) error {
+	if w.names == nil {
+		return nil
+	}
+	return w.names.add(name)
+}
+
+// entryWriter sets the position of the new entry to the central directory header,
+// and returns the writer for the entry. The entry is added by addEntry.
+func (w *Writer) entryWriter(h *centralDirectoryHeader) (io.WriteSeeker, error) {
+	if w.count >= math.MaxUint16 {
+		return nil, wrapError(ErrUnsupported, "number of entries exceeds %d (ZIP64)", math.MaxUint16)
+	}
+
+	if w.repro != nil {
+		// the position is set when the entries are sorted
+		return w.repro.writer(h), nil
+	}
+
+	if err := w.locate(h); err != nil {
+		return nil, err
+	}
+	return w.w, nil
+}
+
+// addEntry adds the central directory header of the entry written by entryWriter.
+func (w *Writer) addEntry(h *centralDirectoryHeader) {
+	w.count++
+	if w.repro != nil {
+		w.repro.add(h)
+		return
+	}
+	w.dirs = append(w.dirs, h)
+}
+
+// locate sets the disk number and the offset of the local file header to the central directory header.
+func (w *Writer) locate(h *centralDirectoryHeader) error {
+	if err := w.reserve(sizeLocalFileHeader + len(h.fileName)); err != nil {
+		return err
+	}
+	disk, offset, err := w.position()
+	if err != nil {
+		return err
+	}
+	if offset > math.MaxUint32 {
+		return newError(ErrUnsupported, string(h.fileName), offset, "local file header offset exceeds %d (ZIP64)", uint32(math.MaxUint32))
+	}
+	h.diskNumber = disk
+	h.localHeaderOffset = uint32(offset)
+	return nil
+}
+
+// position returns the current disk number and offset in the disk.
+func (w *Writer) position() (uint16, int64, error) {
+	if w.vw != nil {
+		return uint16(w.vw.disk), w.vw.offset, nil
+	}
+	offset, err := w.w.Seek(0, io.SeekCurrent)
+	return 0, offset, err
+}
+
+// reserve ensures that the next n bytes are written to the same volume.
+func (w *Writer) reserve(n int) error {
+	if w.vw != nil {
+		return w.vw.reserve(int64(n))
+	}
+	return nil
+}
+
 // writeCentralDirectories writes central directory headers.
 func (w *Writer) writeCentralDirectories() error {
 	startOffset, err := w.w.Seek(0, io.SeekCurrent)
@@ -170,10 +480,40 @@ func (w *Writer) writeCentralDirectories() error {
 		return err
 	}
 
-	for _, dir := range w.dirs {
-		if _, err := dir.WriteTo(w.w); err != nil {
+	var (
+		startDisk    uint16
+		startDirDisk uint16
+		dirOffset    int64
+		entriesDisk  int
+		i            int
+	)
+	err = w.eachDir(func(dir *centralDirectoryHeader) error {
+		buf := new(bytes.Buffer)
+		if _, err := dir.WriteTo(buf); err != nil {
 			return err
 		}
+		if err := w.reserve(buf.Len()); err != nil {
+			return err
+		}
+
+		disk, offset, err := w.position()
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			startDirDisk, dirOffset = disk, offset
+		}
+		if i == 0 || disk != startDisk {
+			startDisk, entriesDisk = disk, 0
+		}
+		entriesDisk++
+		i++
+
+		_, err = w.w.Write(buf.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
 	endOffset, err := w.w.Seek(0, io.SeekCurrent)
@@ -181,11 +521,30 @@ func (w *Writer) writeCentralDirectories() error {
 		return err
 	}
 
+	if err := w.reserve(sizeEndCentralDirectory + len(w.Comment)); err != nil {
+		return err
+	}
+	disk, offset, err := w.position()
+	if err != nil {
+		return err
+	}
+	if i == 0 {
+		startDirDisk, dirOffset = disk, offset
+	}
+	if i == 0 || disk != startDisk {
+		entriesDisk = 0
+	}
+	if dirOffset > math.MaxUint32 || endOffset-startOffset > math.MaxUint32 {
+		return wrapError(ErrUnsupported, "central directory offset=%d size=%d exceeds %d (ZIP64)", dirOffset, endOffset-startOffset, uint32(math.MaxUint32))
+	}
+
 	end := &endCentralDirectory{
-		numberOfEntriesThisDisk:  uint16(len(w.dirs)),
-		numberOfEntries:          uint16(len(w.dirs)),
+		numberOfDisk:             disk,
+		numberOfStartDirDisk:     startDirDisk,
+		numberOfEntriesThisDisk:  uint16(entriesDisk),
+		numberOfEntries:          uint16(i),
 		sizeOfCentralDirectories: uint32(endOffset - startOffset),
-		offsetCentralDirectory:   uint32(startOffset),
+		offsetCentralDirectory:   uint32(dirOffset),
 		comment:                  []byte(w.Comment),
 	}
 
@@ -197,8 +556,9 @@ func (w *Writer) writeCentralDirectories() error {
 
 // fileWriter implements a io.WriteCloser that writes compressed data.
 type fileWriter struct {
-	w io.WriteSeeker          // raw Writer
-	h *centralDirectoryHeader // reference to central directory header
+	zw *Writer                 // zip Writer that the entry is added to at Close
+	w  io.WriteSeeker          // raw Writer
+	h  *centralDirectoryHeader // reference to central directory header
 
 	compCounter   *countWriter   // compress size counter
 	compWriter    io.WriteCloser // compress Writer
@@ -206,14 +566,20 @@ type fileWriter struct {
 	crc32         hash.Hash32    // hash calclator
 	fw            io.Writer      // file data Writer
 	fh            *FileHeader
+	probe         *bytes.Buffer // first block to choose the method (nil if chosen)
 	initialized   bool
 	closed        bool
 }
 
 // Write compresses and writes []byte.
 func (fw *fileWriter) Write(p []byte) (int, error) {
+	if fw.probe != nil {
+		return fw.probeWrite(p)
+	}
 	if !fw.initialized {
-		fw.writeInit()
+		if err := fw.writeInit(); err != nil {
+			return 0, err
+		}
 	}
 
 	return fw.fw.Write(p)
@@ -223,27 +589,50 @@ func (fw *fileWriter) Write(p []byte) (int, error) {
 // If FlagDataDescriptor is not set, the file header is rewritten.
 func (fw *fileWriter) Close() error {
 	if fw.closed {
-		return errors.New("already closed")
+		return fs.ErrClosed
 	}
 	fw.closed = true
 
-	if fw.initialized {
-		if err := fw.compWriter.Close(); err != nil {
+	if fw.probe != nil {
+		if err := fw.chooseMethod(); err != nil {
+			return err
+		}
+	}
+	if !fw.initialized {
+		// empty file also needs the file header
+		if err := fw.writeInit(); err != nil {
 			return err
 		}
-		fw.fh.CRC32 = fw.crc32.Sum32()
-		fw.fh.CompressedSize = uint32(fw.compCounter.Count)
-		fw.fh.UncompressedSize = uint32(fw.uncompCounter.Count)
 	}
+	if err := fw.compWriter.Close(); err != nil {
+		return err
+	}
+	if int64(fw.compCounter.Count) > math.MaxUint32 || int64(fw.uncompCounter.Count) > math.MaxUint32 {
+		return newError(ErrUnsupported, fw.fh.FileName, -1, "file size exceeds %d (ZIP64)", uint32(math.MaxUint32))
+	}
+	fw.fh.CRC32 = fw.crc32.Sum32()
+	fw.fh.CompressedSize = uint32(fw.compCounter.Count)
+	fw.fh.UncompressedSize = uint32(fw.uncompCounter.Count)
 	if err := fw.h.copyFromHeader(fw.fh); err != nil {
 		return err
 	}
 
+	var err error
 	if fw.fh.Flags.DataDescriptor {
-		return fw.writeDataDescriptor()
+		err = fw.writeDataDescriptor()
 	} else {
-		return fw.rewriteFileHeader()
+		err = fw.rewriteFileHeader()
+	}
+	if err != nil {
+		return err
+	}
+
+	// the entry is added after the data is written successfully
+	if err := fw.zw.addName(fw.fh.FileName); err != nil {
+		return err
 	}
+	fw.zw.addEntry(fw.h)
+	return nil
 }
 
 // IsClosed returns whether the fileWriter is closed.
@@ -254,13 +643,6 @@ func (fw *fileWriter) IsClosed() bool {
 // writeInit performs the preprocessing for writing and write the file header.
 func (fw *fileWriter) writeInit() error {
 	var err error
-	fw.initialized = true
-
-	fw.fh = &FileHeader{}
-	if err := fw.h.copyToHeader(fw.fh); err != nil {
-		return err
-	}
-
 	fw.compCounter = &countWriter{w: fw.w}
 	fw.compWriter, err = fw.fh.Method.newCompressor(fw.compCounter)
 	if err != nil {
@@ -274,7 +656,11 @@ func (fw *fileWriter) writeInit() error {
 		fw.crc32,
 	)
 
-	return fw.writeFileHeader()
+	if err := fw.writeFileHeader(); err != nil {
+		return err
+	}
+	fw.initialized = true
+	return nil
 }
 
 // writeFileHeader writes the file header.
@@ -1,9 +1,16 @@
 package zip
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"go-mylib/buffer"
+	"hash/crc32"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -204,3 +211,271 @@ func TestWriterCopyFromReader(t *testing.T) {
 		})
 	}
 }
+
+// errReader implements io.Reader that fails after the data.
+type errReader struct {
+	data string
+	err  error
+}
+
+// Read implements the standard Read interface.
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestWriterCopyFromReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	for _, reproducible := range []bool{false, true} {
+		buf := new(buffer.Buffer)
+		zw, err := NewWriterWithOptions(buffer.NewWriter(buf), &WriterOptions{Reproducible: reproducible})
+		if err != nil {
+			t.Fatalf("NewWriterWithOptions error=%v", err)
+		}
+		fh := NewFileHeader("a.txt")
+		fh.Method = &MethodStore{}
+		fh.CompressedSize = 7
+		fh.UncompressedSize = 7
+		fh.CRC32 = crc32.ChecksumIEEE([]byte("content"))
+		if err := zw.CopyFromReader(fh, &errReader{data: "con", err: boom}); !errors.Is(err, boom) {
+			t.Fatalf("reproducible=%v Writer.CopyFromReader error=%v, want=%v", reproducible, err, boom)
+		}
+		// the name of the failed entry can be written again
+		if err := zw.CopyFromReader(fh, &errReader{data: "content", err: io.EOF}); err != nil {
+			t.Fatalf("reproducible=%v Writer.CopyFromReader error=%v", reproducible, err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("reproducible=%v Writer.Close error=%v", reproducible, err)
+		}
+
+		// the failed entry is not in the central directory
+		zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reproducible=%v NewReader error=%v", reproducible, err)
+		}
+		if len(zr.Files) != 1 {
+			t.Fatalf("reproducible=%v files=%d, want=1", reproducible, len(zr.Files))
+		}
+		if got := readAll(t, zr.Files[0]); got != "content" {
+			t.Fatalf("reproducible=%v content=%q, want=%q", reproducible, got, "content")
+		}
+	}
+}
+
+func TestWriterPrefix(t *testing.T) {
+	tt := tests["no-data-descriptor"]
+	prefix := "#!/bin/sh\nexit 0\n"
+
+	buf := new(buffer.Buffer)
+	opts := &WriterOptions{
+		Prefix: strings.NewReader(prefix),
+	}
+	zw, err := NewWriterWithOptions(buffer.NewWriter(buf), opts)
+	if err != nil {
+		t.Fatalf("NewWriterWithOptions error=%#v", err)
+	}
+
+	fh := NewFileHeader(tt.filename)
+	fh.Flags = tt.flags
+	fh.ModifiedTime = tt.mtime
+	fw, err := zw.CreateFromHeader(fh)
+	if err != nil {
+		t.Fatalf("Writer.Create error=%#v", err)
+	}
+	if _, err := fw.Write([]byte(tt.content)); err != nil {
+		t.Fatalf("FileWriter.Write error=%#v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%#v", err)
+	}
+
+	if got := string(buf.Bytes()[:len(prefix)]); got != prefix {
+		t.Errorf("prefix=%q, want=%q", got, prefix)
+	}
+
+	zr, err := NewReader(buffer.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewReader error=%#v", err)
+	}
+	if zr.BaseOffset() != 0 {
+		t.Errorf("BaseOffset=%d, want=%d", zr.BaseOffset(), 0)
+	}
+	testcaseCompare(t, buffer.NewReader(buf), tt)
+}
+
+func TestWriterLimits(t *testing.T) {
+	long := strings.Repeat("a", 0x10000)
+	tests := []struct {
+		name  string
+		setup func(fh *FileHeader, zw *Writer)
+	}{
+		{"file-name", func(fh *FileHeader, zw *Writer) { fh.FileName = long }},
+		{"file-comment", func(fh *FileHeader, zw *Writer) { fh.Comment = long }},
+		{"extra-fields", func(fh *FileHeader, zw *Writer) {
+			fh.ExtraFields = append(fh.ExtraFields, &ExtraUnknown{Data: []byte(long[:0x8000])}, &ExtraUnknown{Data: []byte(long[:0x8000])})
+		}},
+		{"archive-comment", func(fh *FileHeader, zw *Writer) { zw.Comment = long }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, copy := range []bool{false, true} {
+				buf := new(buffer.Buffer)
+				zw, err := NewWriter(buffer.NewWriter(buf))
+				if err != nil {
+					t.Fatalf("NewWriter error=%v", err)
+				}
+				fh := NewFileHeader("a.txt")
+				tt.setup(fh, zw)
+
+				if copy {
+					err = zw.CopyFromReader(fh, strings.NewReader(""))
+				} else {
+					_, err = zw.CreateFromHeader(fh)
+				}
+				if err == nil {
+					err = zw.Close()
+				}
+				if !errors.Is(err, fs.ErrInvalid) {
+					t.Fatalf("copy=%v error=%v, want=%v", copy, err, fs.ErrInvalid)
+				}
+				if zw.Comment == "" && buf.Len() != 0 {
+					t.Fatalf("written size=%d, want=0", buf.Len())
+				}
+			}
+		})
+	}
+}
+
+func TestWriterEntries(t *testing.T) {
+	zw, err := NewWriterWithOptions(buffer.NewWriter(new(buffer.Buffer)), &WriterOptions{AllowDuplicates: true})
+	if err != nil {
+		t.Fatalf("NewWriterWithOptions error=%v", err)
+	}
+	fh := NewFileHeader("a.txt")
+	fh.Method = &MethodStore{}
+	for i := 0; i < 0xffff; i++ {
+		if err := zw.CopyFromReader(fh, bytes.NewReader(nil)); err != nil {
+			t.Fatalf("Writer.CopyFromReader error=%v", err)
+		}
+	}
+	if _, err := zw.Create("a.txt"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Writer.Create error=%v, want=%v", err, ErrUnsupported)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Writer.Close error=%v", err)
+	}
+}
+
+func TestWriterMinimumVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(fh *FileHeader)
+		want  int
+	}{
+		{"store", func(fh *FileHeader) { fh.Method = &MethodStore{} }, 10},
+		{"deflate", func(fh *FileHeader) {}, 20},
+		{"directory", func(fh *FileHeader) { fh.FileName = "dir/" }, 20},
+		{"utf8", func(fh *FileHeader) { fh.Flags.UTF8 = true }, 20},
+		{"utf8-store", func(fh *FileHeader) { fh.Flags.UTF8 = true; fh.Method = &MethodStore{} }, 10},
+		{"zip64", func(fh *FileHeader) {
+			fh.ExtraFields = append(fh.ExtraFields, &ExtraUnknown{tag: extraZip64Tag, Data: append([]byte{0x01, 0x00, 0x08, 0x00}, make([]byte, 8)...)})
+		}, 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			fh := NewFileHeader("a.txt")
+			tt.setup(fh)
+			if _, err := zw.CreateFromHeader(fh); err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if fh.MinimumVersion != tt.want {
+				t.Fatalf("MinimumVersion=%d, want=%d", fh.MinimumVersion, tt.want)
+			}
+			if fh.GenerateVersion < fh.MinimumVersion {
+				t.Fatalf("GenerateVersion=%d, want>=%d", fh.GenerateVersion, fh.MinimumVersion)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			if got := zr.Files[0].MinimumVersion; got != tt.want {
+				t.Fatalf("read MinimumVersion=%d, want=%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterEmptyFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(fh *FileHeader)
+		method uint16
+	}{
+		{"store", func(fh *FileHeader) { fh.Method = &MethodStore{} }, methodStoreID},
+		{"deflate", func(fh *FileHeader) {}, methodDeflatedID},
+		{"data-descriptor", func(fh *FileHeader) { fh.Flags.DataDescriptor = true }, methodDeflatedID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(buffer.Buffer)
+			zw, err := NewWriter(buffer.NewWriter(buf))
+			if err != nil {
+				t.Fatalf("NewWriter error=%v", err)
+			}
+			// the file is closed without Write, but the file header is written
+			fh := NewFileHeader("empty.txt")
+			tt.setup(fh)
+			fw, err := zw.CreateFromHeader(fh)
+			if err != nil {
+				t.Fatalf("Writer.CreateFromHeader error=%v", err)
+			}
+			if err := fw.Close(); err != nil {
+				t.Fatalf("FileWriter.Close error=%v", err)
+			}
+			if _, err := zw.Create("next.txt"); err != nil {
+				t.Fatalf("Writer.Create error=%v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Writer.Close error=%v", err)
+			}
+
+			zr, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader error=%v", err)
+			}
+			if len(zr.Files) != 2 {
+				t.Fatalf("files=%d, want=2", len(zr.Files))
+			}
+			f := zr.Files[0]
+			if f.Method.ID() != tt.method {
+				t.Fatalf("method=%d, want=%d", f.Method.ID(), tt.method)
+			}
+			if got := readAll(t, f); got != "" {
+				t.Fatalf("content=%q, want empty", got)
+			}
+			report, err := zr.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Reader.Verify error=%v", err)
+			}
+			if !report.OK() {
+				t.Fatalf("Reader.Verify report=%+v", report)
+			}
+		})
+	}
+}